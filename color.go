@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"golang.org/x/term"
+)
+
+// ANSI escape codes used to color log lines by event type.
+const (
+	colorReset  = "\033[0m"
+	colorRed    = "\033[31m"
+	colorGreen  = "\033[32m"
+	colorYellow = "\033[33m"
+	colorCyan   = "\033[36m"
+)
+
+// eventColors maps the logged event types to their color.
+var eventColors = map[string]string{
+	"ADDED":    colorGreen,
+	"RE-ADDED": colorGreen,
+	"MODIFIED": colorYellow,
+	"DELETED":  colorRed,
+	"ERROR":    colorRed,
+	"BOOKMARK": colorCyan,
+}
+
+// useColor resolves the -color mode for out. In auto mode colors are only
+// used when out is a terminal.
+func useColor(out io.Writer, mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		f, ok := out.(*os.File)
+		return ok && term.IsTerminal(int(f.Fd())), nil
+	default:
+		return false, fmt.Errorf("unsupported color mode %q", mode)
+	}
+}
+
+// colorHandler is a slog.Handler coloring each line by the event_type
+// attribute of the record, or red for errors.
+type colorHandler struct {
+	slog.Handler
+	state *colorState
+}
+
+// colorState is shared by a colorHandler and the handlers derived from it.
+type colorState struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	out io.Writer
+}
+
+// newColorHandler returns a colorHandler writing to out. newHandler creates
+// the formatting handler for the given writer.
+func newColorHandler(out io.Writer, newHandler func(io.Writer) slog.Handler) *colorHandler {
+	state := &colorState{out: out}
+	return &colorHandler{Handler: newHandler(&state.buf), state: state}
+}
+
+// Handle implements slog.Handler.
+func (h *colorHandler) Handle(ctx context.Context, r slog.Record) error {
+	color := ""
+	if r.Level >= slog.LevelError {
+		color = colorRed
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		if a.Key != "event_type" {
+			return true
+		}
+		if c, ok := eventColors[a.Value.String()]; ok {
+			color = c
+		}
+		return false
+	})
+
+	h.state.mu.Lock()
+	defer h.state.mu.Unlock()
+	h.state.buf.Reset()
+	if err := h.Handler.Handle(ctx, r); err != nil {
+		return err
+	}
+	line := bytes.TrimSuffix(h.state.buf.Bytes(), []byte("\n"))
+	if color == "" {
+		_, err := fmt.Fprintf(h.state.out, "%s\n", line)
+		return err
+	}
+	_, err := fmt.Fprintf(h.state.out, "%s%s%s\n", color, line, colorReset)
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *colorHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &colorHandler{Handler: h.Handler.WithAttrs(attrs), state: h.state}
+}
+
+// WithGroup implements slog.Handler.
+func (h *colorHandler) WithGroup(name string) slog.Handler {
+	return &colorHandler{Handler: h.Handler.WithGroup(name), state: h.state}
+}
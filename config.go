@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// connectionOptions holds the flags describing how to reach the API server.
+type connectionOptions struct {
+	kubeconfig string
+	inCluster  bool
+	// context selects a kubeconfig context other than the current one
+	context string
+
+	// Direct authentication without a kubeconfig
+	server     string
+	token      string
+	tokenFile  string
+	caCert     string
+	clientCert string
+	clientKey  string
+
+	// Client behaviour
+	insecureSkipTLSVerify bool
+	qps                   float64
+	burst                 int
+	protobuf              bool
+	userAgent             string
+	traceRequests         bool
+	disableCompression    bool
+	suppressWarnings      bool
+
+	// serverURL overrides the API server address, e.g. of kubectl proxy
+	serverURL string
+}
+
+// direct reports whether any of the direct authentication flags are set.
+func (o connectionOptions) direct() bool {
+	return o.server != "" || o.token != "" || o.tokenFile != "" || o.caCert != "" || o.clientCert != "" || o.clientKey != ""
+}
+
+// newClients builds the rest config and applies the client settings of opts
+// before creating the typed clientset and the dynamic client for custom
+// resources.
+func newClients(logger *slog.Logger, opts connectionOptions) (*kubernetes.Clientset, *dynamic.DynamicClient, error) {
+	config, err := buildConfig(logger, opts)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating config: %w", err)
+	}
+
+	// Point the client at a proxy or another address
+	if opts.serverURL != "" {
+		if err := overrideServer(config, opts.serverURL); err != nil {
+			return nil, nil, err
+		}
+		logger.Info("Overriding the API server address", "server_url", opts.serverURL, "host", config.Host)
+	}
+
+	// Skip certificate verification for clusters with self-signed certificates
+	if opts.insecureSkipTLSVerify {
+		logger.Warn("TLS CERTIFICATE VERIFICATION IS DISABLED, the connection to the API server is not secure")
+		config.TLSClientConfig.Insecure = true
+		config.TLSClientConfig.CAData = nil
+		config.TLSClientConfig.CAFile = ""
+	}
+
+	// Raise the client throttling for watching many resources
+	config.QPS = float32(opts.qps)
+	config.Burst = opts.burst
+	logger.Info("Client rate limits", "qps", config.QPS, "burst", config.Burst)
+
+	// Negotiate protobuf with JSON as a fallback for types without it
+	if opts.protobuf {
+		config.ContentType = runtime.ContentTypeProtobuf
+		config.AcceptContentTypes = runtime.ContentTypeProtobuf + "," + runtime.ContentTypeJSON
+		logger.Info("Using protobuf content type", "content_type", config.ContentType)
+	}
+
+	// Let the transport request gzip responses, which shrinks large lists
+	config.DisableCompression = opts.disableCompression
+	logger.Info("Transport compression", "enabled", !config.DisableCompression)
+
+	// Surface deprecations of the watched API versions in the log
+	if opts.suppressWarnings {
+		config.WarningHandler = rest.NoWarnings{}
+	} else {
+		config.WarningHandler = warningLogger{logger: logger}
+	}
+
+	// Identify the client in the API server audit logs
+	config.UserAgent = opts.userAgent
+
+	// Show when the API server throttles the client
+	config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+		return newThrottleRoundTripper(logger, rt)
+	})
+
+	// Log the HTTP requests behind the watches
+	if opts.traceRequests {
+		config.Wrap(func(rt http.RoundTripper) http.RoundTripper {
+			return &tracingRoundTripper{logger: logger, next: rt}
+		})
+	}
+
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The dynamic client always uses JSON, whatever the content type
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, dynamicClient, nil
+}
+
+// buildConfig returns the rest config used to talk to the API server. Direct
+// authentication flags take precedence, otherwise it first tries the
+// in-cluster service account environment and falls back to the kubeconfig
+// file when not running inside a Pod, unless inCluster forces it. Without
+// -kubeconfig the files listed in $KUBECONFIG are used, if set.
+func buildConfig(logger *slog.Logger, opts connectionOptions) (*rest.Config, error) {
+	// A local proxy needs no credentials, so no kubeconfig is required
+	if opts.serverURL != "" && !opts.direct() && opts.kubeconfig == "" && !opts.inCluster {
+		if u, err := url.Parse(opts.serverURL); err == nil && (u.Scheme == "http" || u.Scheme == "unix") {
+			logger.Info("Using proxied API server without credentials", "server_url", opts.serverURL)
+			return &rest.Config{}, nil
+		}
+	}
+
+	if opts.direct() {
+		if opts.kubeconfig != "" || opts.inCluster {
+			return nil, errors.New("direct authentication flags cannot be combined with -kubeconfig or -in-cluster")
+		}
+		logger.Info("Using direct authentication", "server", opts.server)
+		return directConfig(opts)
+	}
+
+	// Select the context among the merged kubeconfig files like kubectl
+	if opts.context != "" {
+		if opts.inCluster {
+			return nil, errors.New("-context cannot be combined with -in-cluster")
+		}
+		logger.Info("Using kubeconfig context", "context", opts.context)
+		rules := clientcmd.NewDefaultClientConfigLoadingRules()
+		rules.ExplicitPath = opts.kubeconfig
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: opts.context}).ClientConfig()
+	}
+
+	config, err := rest.InClusterConfig()
+	if err == nil {
+		logger.Info("Using in-cluster configuration")
+		return config, nil
+	}
+	if opts.inCluster {
+		return nil, fmt.Errorf("no in-cluster environment: %w", err)
+	}
+	logger.Info("In-cluster configuration unavailable, falling back to kubeconfig", "reason", err)
+
+	kubeconfig := opts.kubeconfig
+
+	// Honor $KUBECONFIG like kubectl, merging the files it lists
+	if env := os.Getenv(clientcmd.RecommendedConfigPathEnvVar); kubeconfig == "" && env != "" {
+		logger.Info("Using kubeconfig from environment", "paths", env)
+		rules := &clientcmd.ClientConfigLoadingRules{Precedence: filepath.SplitList(env)}
+		return clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{}).ClientConfig()
+	}
+
+	// Fall back to the default kubeconfig path for Kind
+	if kubeconfig == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get home directory: %w", err)
+		}
+		kubeconfig = filepath.Join(homeDir, ".kube", "config")
+	}
+
+	// Make sure the kubeconfig file exists before using it
+	if _, err := os.Stat(kubeconfig); err != nil {
+		return nil, fmt.Errorf("%w at %s: %w", watcher.ErrKubeconfigNotFound, kubeconfig, err)
+	}
+
+	logger.Info("Using kubeconfig", "path", kubeconfig)
+	return clientcmd.BuildConfigFromFlags("", kubeconfig)
+}
+
+// directConfig builds a rest config from the direct authentication flags,
+// requiring the server and either a token, a token file or a client
+// certificate and key. A token file is reread by client-go as it rotates.
+func directConfig(opts connectionOptions) (*rest.Config, error) {
+	if opts.server == "" {
+		return nil, errors.New("-server is required for direct authentication")
+	}
+	if (opts.clientCert == "") != (opts.clientKey == "") {
+		return nil, errors.New("-client-cert and -client-key must be set together")
+	}
+	if opts.token != "" && opts.tokenFile != "" {
+		return nil, errors.New("-token and -token-file cannot be combined")
+	}
+	if opts.token == "" && opts.tokenFile == "" && opts.clientCert == "" {
+		return nil, errors.New("direct authentication requires -token, -token-file or -client-cert and -client-key")
+	}
+	return &rest.Config{
+		Host:            opts.server,
+		BearerToken:     opts.token,
+		BearerTokenFile: opts.tokenFile,
+		TLSClientConfig: rest.TLSClientConfig{
+			CAFile:   opts.caCert,
+			CertFile: opts.clientCert,
+			KeyFile:  opts.clientKey,
+		},
+	}, nil
+}
+
+// overrideServer points config at serverURL. Plain HTTP, as served by
+// kubectl proxy, and unix:// sockets skip the TLS configuration entirely.
+func overrideServer(config *rest.Config, serverURL string) error {
+	u, err := url.Parse(serverURL)
+	if err != nil {
+		return fmt.Errorf("invalid server URL: %w", err)
+	}
+	switch u.Scheme {
+	case "https":
+		config.Host = serverURL
+	case "http":
+		config.Host = serverURL
+		config.TLSClientConfig = rest.TLSClientConfig{}
+	case "unix":
+		// Send plain HTTP requests over the socket, e.g. kubectl proxy -u
+		socket := u.Path
+		config.Host = "http://localhost"
+		config.TLSClientConfig = rest.TLSClientConfig{}
+		config.Dial = func(ctx context.Context, network, address string) (net.Conn, error) {
+			var dialer net.Dialer
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	default:
+		return fmt.Errorf("unsupported server URL scheme %q, expected http, https or unix", u.Scheme)
+	}
+	return nil
+}
+
+// contextNamespace returns the namespace of the kubeconfig context
+// contextName, or of the current one when empty, or of the service account
+// when running in a Pod, falling back to "default" when none is set.
+func contextNamespace(logger *slog.Logger, kubeconfig, contextName string) string {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = kubeconfig
+	namespace, _, err := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, &clientcmd.ConfigOverrides{CurrentContext: contextName}).Namespace()
+	if err != nil || namespace == "" {
+		logger.Info("No namespace in the current context, using default", "reason", err)
+		return metav1.NamespaceDefault
+	}
+	logger.Info("Using the namespace of the current context", "namespace", namespace)
+	return namespace
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
+)
+
+// dumpOnSignal writes the state of the watches, the tracked Pods and the
+// event counts to out whenever dumpSignals is received, without stopping
+// the watch, until ctx is cancelled.
+func dumpOnSignal(ctx context.Context, logger *slog.Logger, out io.Writer, states func() []watcher.State, pods *watcher.PodTracker, stats *watcher.Stats) {
+	if len(dumpSignals) == 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, dumpSignals...)
+	defer signal.Stop(signals)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-signals:
+			// Render into a buffer so the dump is not interleaved with logs
+			var buf bytes.Buffer
+			err := writeDump(&buf, states(), pods, stats)
+			if err == nil {
+				_, err = out.Write(buf.Bytes())
+			}
+			if err != nil {
+				logger.Warn("Failed to dump state", "error", err)
+			}
+		}
+	}
+}
+
+// writeDump writes the tables of the dump to out.
+func writeDump(out io.Writer, states []watcher.State, pods *watcher.PodTracker, stats *watcher.Stats) error {
+	fmt.Fprintln(out, "=== Watches")
+	if err := watcher.WriteStates(out, states); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "=== Pods")
+	if err := pods.WriteTable(out); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, "=== Events")
+	return stats.WriteTable(out)
+}
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// dumpSignals is empty on platforms without SIGUSR1.
+var dumpSignals []os.Signal
@@ -0,0 +1,11 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// dumpSignals trigger a dump of the current state.
+var dumpSignals = []os.Signal{syscall.SIGUSR1}
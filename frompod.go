@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// siblingLabels lists the Pod labels identifying a workload, in order of
+// preference, used when the Pod has no controller with a selector.
+var siblingLabels = []string{"app.kubernetes.io/instance", "app.kubernetes.io/name", "app"}
+
+// podSelector returns a label selector matching the Pod name in namespace
+// and its siblings: the selector of the Deployment, ReplicaSet, StatefulSet,
+// DaemonSet or Job controlling it, or else its app label.
+func podSelector(ctx context.Context, clientset kubernetes.Interface, namespace, name string) (string, error) {
+	pod, err := clientset.CoreV1().Pods(namespace).Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	if owner := metav1.GetControllerOf(pod); owner != nil {
+		selector, err := controllerSelector(ctx, clientset, namespace, owner)
+		if err != nil {
+			return "", fmt.Errorf("getting the selector of %s %s: %w", owner.Kind, owner.Name, err)
+		}
+		if selector != nil {
+			parsed, err := metav1.LabelSelectorAsSelector(selector)
+			if err != nil {
+				return "", fmt.Errorf("invalid selector of %s %s: %w", owner.Kind, owner.Name, err)
+			}
+			return parsed.String(), nil
+		}
+	}
+	return appSelector(pod)
+}
+
+// controllerSelector returns the Pod selector of the controller owner, or
+// nil for a kind without one.
+func controllerSelector(ctx context.Context, clientset kubernetes.Interface, namespace string, owner *metav1.OwnerReference) (*metav1.LabelSelector, error) {
+	switch owner.Kind {
+	case "ReplicaSet":
+		rs, err := clientset.AppsV1().ReplicaSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		// The selector of a ReplicaSet of a Deployment pins its
+		// pod-template-hash, use the Deployment's to span its rollouts
+		if deployment := metav1.GetControllerOf(rs); deployment != nil && deployment.Kind == "Deployment" {
+			return controllerSelector(ctx, clientset, namespace, deployment)
+		}
+		return rs.Spec.Selector, nil
+	case "Deployment":
+		deployment, err := clientset.AppsV1().Deployments(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return deployment.Spec.Selector, nil
+	case "StatefulSet":
+		sts, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return sts.Spec.Selector, nil
+	case "DaemonSet":
+		ds, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return ds.Spec.Selector, nil
+	case "Job":
+		job, err := clientset.BatchV1().Jobs(namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+		if err != nil {
+			return nil, err
+		}
+		return job.Spec.Selector, nil
+	default:
+		return nil, nil
+	}
+}
+
+// appSelector returns a selector on the first of the siblingLabels set on
+// pod.
+func appSelector(pod *v1.Pod) (string, error) {
+	for _, key := range siblingLabels {
+		if value, ok := pod.Labels[key]; ok {
+			return labels.SelectorFromSet(labels.Set{key: value}).String(), nil
+		}
+	}
+	return "", fmt.Errorf("pod %s has no controller and none of the labels %v", pod.Name, siblingLabels)
+}
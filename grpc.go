@@ -0,0 +1,106 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"time"
+
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/eventstream"
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
+	"google.golang.org/grpc"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// grpcSubscriberBuffer is how many events are queued for a gRPC client
+// before further events are dropped for it.
+const grpcSubscriberBuffer = 256
+
+// eventStreamServer implements the EventStream gRPC service on top of a
+// Broadcaster.
+type eventStreamServer struct {
+	eventstream.UnimplementedEventStreamServer
+	ctx         context.Context
+	logger      *slog.Logger
+	broadcaster *watcher.Broadcaster
+}
+
+// StreamPodEvents implements eventstream.EventStreamServer.
+func (s *eventStreamServer) StreamPodEvents(_ *eventstream.StreamPodEventsRequest, stream eventstream.EventStream_StreamPodEventsServer) error {
+	events, unsubscribe := s.broadcaster.Subscribe()
+	defer unsubscribe()
+	s.logger.Info("gRPC client subscribed")
+	defer s.logger.Info("gRPC client unsubscribed")
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return nil
+		case <-stream.Context().Done():
+			return nil
+		case event := <-events:
+			if event.Type == watch.Bookmark {
+				continue
+			}
+			if err := stream.Send(podEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// podEvent converts a watch event into its protobuf message.
+func podEvent(event watcher.Event) *eventstream.PodEvent {
+	message := &eventstream.PodEvent{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Type:      string(event.Type),
+	}
+	// Typed objects decoded from a watch carry no kind of their own
+	if gvks, _, err := scheme.Scheme.ObjectKinds(event.Object); err == nil && len(gvks) > 0 {
+		message.Kind = gvks[0].Kind
+	}
+	if accessor, err := meta.Accessor(event.Object); err == nil {
+		message.Namespace = accessor.GetNamespace()
+		message.Name = accessor.GetName()
+		message.ResourceVersion = accessor.GetResourceVersion()
+	}
+	if pod, ok := event.Object.(*v1.Pod); ok {
+		message.Phase = string(pod.Status.Phase)
+	}
+	return message
+}
+
+// serveGRPC serves the EventStream service on addr, streaming the events of
+// broadcaster, until ctx is cancelled.
+func serveGRPC(ctx context.Context, logger *slog.Logger, addr string, broadcaster *watcher.Broadcaster) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		logger.Error("Server failed", "server", "grpc", "error", err)
+		return
+	}
+	server := grpc.NewServer()
+	eventstream.RegisterEventStreamServer(server, &eventStreamServer{ctx: ctx, logger: logger, broadcaster: broadcaster})
+
+	go func() {
+		<-ctx.Done()
+		// The streams end with ctx, so a graceful stop only waits for sends
+		stopped := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			server.Stop()
+		}
+	}()
+
+	logger.Info("Starting server", "server", "grpc", "addr", addr)
+	if err := server.Serve(listener); err != nil {
+		logger.Error("Server failed", "server", "grpc", "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// kindWait is how long kind waits for the control plane to become ready.
+const kindWait = "2m"
+
+// createKindCluster creates the Kind cluster name unless it already exists,
+// waiting for its control plane to be ready, and returns the path of its
+// kubeconfig file, readable only by the current user and replaced on every
+// call.
+func createKindCluster(ctx context.Context, logger *slog.Logger, name string) (string, error) {
+	clusters, err := kind(ctx, "get", "clusters")
+	if err != nil {
+		return "", err
+	}
+	if slices.Contains(strings.Fields(clusters), name) {
+		logger.Info("Using existing Kind cluster", "name", name)
+	} else {
+		logger.Info("Creating Kind cluster, this can take a few minutes", "name", name)
+		if _, err := kind(ctx, "create", "cluster", "--name", name, "--wait", kindWait); err != nil {
+			return "", err
+		}
+		logger.Info("Kind cluster is ready", "name", name)
+	}
+
+	kubeconfig, err := kind(ctx, "get", "kubeconfig", "--name", name)
+	if err != nil {
+		return "", err
+	}
+	path, err := kindKubeconfigPath(name)
+	if err != nil {
+		return "", err
+	}
+	if err := writeKubeconfig(path, kubeconfig); err != nil {
+		return "", fmt.Errorf("writing kubeconfig: %w", err)
+	}
+	return path, nil
+}
+
+// kindKubeconfigPath returns the path of the kubeconfig file of the Kind
+// cluster name, in a directory of the user cache that only the current user
+// may access.
+func kindKubeconfigPath(name string) (string, error) {
+	cache, err := os.UserCacheDir()
+	if err != nil {
+		return "", fmt.Errorf("locating the kubeconfig directory: %w", err)
+	}
+	return filepath.Join(cache, "kube-api-streaming-demo", "kind-"+name+".kubeconfig"), nil
+}
+
+// writeKubeconfig replaces the file at path with kubeconfig, readable only
+// by the current user. The file is written next to it and renamed, so an
+// existing file or symlink at path is replaced rather than written through.
+func writeKubeconfig(path, kubeconfig string) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	if err := os.Chmod(dir, 0o700); err != nil {
+		return err
+	}
+	// CreateTemp creates the file with mode 0600
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.WriteString(kubeconfig); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// deleteKindCluster deletes the Kind cluster name and the kubeconfig file
+// written for it.
+func deleteKindCluster(ctx context.Context, logger *slog.Logger, name string) error {
+	logger.Info("Deleting Kind cluster", "name", name)
+	if _, err := kind(ctx, "delete", "cluster", "--name", name); err != nil {
+		return err
+	}
+	path, err := kindKubeconfigPath(name)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("removing kubeconfig: %w", err)
+	}
+	return nil
+}
+
+// kind runs the kind binary with args and returns its standard output.
+func kind(ctx context.Context, args ...string) (string, error) {
+	path, err := exec.LookPath("kind")
+	if errors.Is(err, exec.ErrNotFound) {
+		return "", errors.New("kind is not installed, see https://kind.sigs.k8s.io/docs/user/quick-start/#installation")
+	}
+	if err != nil {
+		return "", err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("kind %s: %w: %s", strings.Join(args, " "), err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
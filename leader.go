@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/leaderelection"
+	"k8s.io/client-go/tools/leaderelection/resourcelock"
+)
+
+// Timings of the leader election, matching the client-go defaults used by
+// controllers.
+const (
+	leaseDuration = 15 * time.Second
+	renewDeadline = 10 * time.Second
+	retryPeriod   = 2 * time.Second
+)
+
+// runAsLeader waits until this replica holds the Lease name in namespace and
+// then calls run. run's context is cancelled when the leadership is lost. It
+// returns the error of run, or nil when ctx is cancelled first.
+func runAsLeader(ctx context.Context, logger *slog.Logger, clientset kubernetes.Interface, name, namespace string, run func(context.Context) error) error {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("getting hostname: %w", err)
+	}
+	identity := hostname + "_" + string(uuid.NewUUID())
+
+	lock := &resourcelock.LeaseLock{
+		LeaseMeta:  metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Client:     clientset.CoordinationV1(),
+		LockConfig: resourcelock.ResourceLockConfig{Identity: identity},
+	}
+
+	// Stop competing for the lease once run returns
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var runErr error
+	logger.Info("Waiting for leadership", "lease", namespace+"/"+name, "identity", identity)
+	leaderelection.RunOrDie(ctx, leaderelection.LeaderElectionConfig{
+		Lock:            lock,
+		ReleaseOnCancel: true,
+		LeaseDuration:   leaseDuration,
+		RenewDeadline:   renewDeadline,
+		RetryPeriod:     retryPeriod,
+		Callbacks: leaderelection.LeaderCallbacks{
+			OnStartedLeading: func(ctx context.Context) {
+				logger.Info("Acquired leadership, starting to watch", "identity", identity)
+				runErr = run(ctx)
+				cancel()
+			},
+			OnStoppedLeading: func() {
+				logger.Info("Leadership released or lost", "identity", identity)
+			},
+			OnNewLeader: func(leader string) {
+				if leader != identity {
+					logger.Info("Another replica is the leader", "leader", leader)
+				}
+			},
+		},
+	})
+	return runErr
+}
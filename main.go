@@ -2,122 +2,943 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
+	"log/slog"
 	"os"
-	"path/filepath"
+	"os/signal"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"text/template"
+	"time"
 
 	v1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
-	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/rest"
 	"k8s.io/component-base/featuregate"
-	"k8s.io/utils/pointer"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
 )
 
+// suppressedReportInterval is how often the number of events dropped by
+// -max-events-per-second is logged.
+const suppressedReportInterval = 10 * time.Second
+
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
+	// Parse command-line flags
+	namespace := flag.String("namespace", os.Getenv("WATCH_NAMESPACE"), "Namespace to watch pods in ($WATCH_NAMESPACE, defaults to the namespace of the current kubeconfig context)")
+	kubeconfig := flag.String("kubeconfig", "", "Path to the kubeconfig file (defaults to $KUBECONFIG or $HOME/.kube/config)")
+	resource := flag.String("resource", envOr("WATCH_RESOURCE", "pods"), "Resource type to watch (pods, deployments, services, configmaps, secrets, endpoints, events, nodes, persistentvolumeclaims, jobs) ($WATCH_RESOURCE)")
+	resources := flag.String("resources", "", "Comma-separated resource types to watch concurrently, overriding -resource")
+	stateFile := flag.String("state-file", "", "File used to persist the last bookmarked resource version across restarts")
+	inCluster := flag.Bool("in-cluster", false, "Force in-cluster configuration from the Pod's service account")
+	selector := flag.String("selector", os.Getenv("LABEL_SELECTOR"), "Label selector to filter watched objects (e.g. app=nginx,tier=frontend) ($LABEL_SELECTOR)")
+	fieldSelector := flag.String("field-selector", "", "Field selector to filter watched objects (e.g. metadata.name=my-pod)")
+	namespaces := flag.String("namespaces", "", "Comma-separated namespaces to watch concurrently, overriding -namespace")
+	allNamespaces := flag.Bool("all-namespaces", false, "Watch objects across all namespaces, ignoring -namespace")
+	timeout := flag.Duration("timeout", 0, "Stop watching after this duration (0 runs until interrupted)")
+	verbose := flag.Bool("verbose", false, "Log container readiness, restart counts and states for Pod events")
+	metricsAddr := flag.String("metrics-addr", ":9090", "Address to serve Prometheus metrics on (empty disables the endpoint)")
+	healthAddr := flag.String("health-addr", ":8080", "Address to serve /healthz and /readyz on (empty disables the probes)")
+	output := flag.String("output", "text", "Event output format (text, ndjson, table)")
+	watchRetries := flag.Int("watch-retries", 5, "Attempts made to create the watch while the API server is unavailable at startup")
+	summary := flag.Bool("summary", false, "Periodically log how many Pods are in each phase instead of every event")
+	summaryInterval := flag.Duration("summary-interval", 10*time.Second, "Interval between phase summaries in -summary mode")
+	watchListClient := flag.Bool("watch-list-client", true, "Enable the WatchListClient feature gate; disable it to fall back to LIST+WATCH")
+	bufferInitial := flag.Bool("buffer-initial", false, "Collect the initial Added events and print them sorted by name once the initial list is complete")
+	dryRun := flag.Bool("dry-run", false, "Validate the configuration and connectivity by fetching the server version, then exit without watching")
+	onlyPhaseChanges := flag.Bool("only-phase-changes", false, "Only log Modified Pod events that change the Pod's phase")
+	server := flag.String("server", "", "API server URL for direct authentication without a kubeconfig")
+	token := flag.String("token", "", "Bearer token for direct authentication")
+	tokenFile := flag.String("token-file", "", "File holding the bearer token for direct authentication, reread as the token rotates")
+	caCert := flag.String("ca-cert", "", "CA certificate file used to verify the API server with direct authentication")
+	clientCert := flag.String("client-cert", "", "Client certificate file for direct authentication")
+	clientKey := flag.String("client-key", "", "Client key file for direct authentication")
+	maxEventsPerSecond := flag.Float64("max-events-per-second", 0, "Limit how many events are printed per second, suppressing the rest (0 disables the limit)")
+	mode := flag.String("mode", "watch", "How to stream objects: a raw watch or a shared informer (watch, informer)")
+	webhookURL := flag.String("webhook-url", "", "URL to POST a JSON notification to whenever a Pod is deleted")
+	owner := flag.String("owner", "", "Only process objects owned by this workload, as kind/name (e.g. deployment/nginx)")
+	tailLogs := flag.Bool("tail-logs", false, "Follow the container logs of Pods once they are running and ready")
+	qps := flag.Float64("qps", float64(rest.DefaultQPS), "Maximum queries per second to the API server")
+	burst := flag.Int("burst", rest.DefaultBurst, "Maximum burst of queries to the API server")
+	bookmarkTimeout := flag.Duration("bookmark-timeout", time.Minute, "How long to wait for the initial-events-end bookmark before treating the initial list as complete (0 waits forever)")
+	colorMode := flag.String("color", "auto", "Color log lines by event type (auto, always, never)")
+	protobuf := flag.Bool("protobuf", false, "Use protobuf instead of JSON to encode requests and watch events")
+	maxEvents := flag.Int("max-events", 0, "Exit after this many events, not counting bookmarks (0 disables the limit)")
+	insecureSkipTLSVerify := flag.Bool("insecure-skip-tls-verify", false, "Do not verify the API server certificate (insecure, for throwaway clusters only)")
+	debounce := flag.Duration("debounce", 0, "Log one aggregated summary per object once no event arrived for this long, instead of every event (0 disables it)")
+	eventType := flag.String("event-type", "all", "Type of Kubernetes Events to watch with -resource events (Warning, Normal, all)")
+	record := flag.String("record", "", "Also write the event stream as NDJSON to this file for a later -replay")
+	replay := flag.String("replay", "", "Replay the events recorded with -record from this file instead of watching a cluster")
+	initialEventsAnnotation := flag.String("initial-events-annotation", metav1.InitialEventsAnnotationKey, "Annotation marking the bookmark sent at the end of the initial list")
+	userAgent := flag.String("user-agent", "kube-api-streaming-demo/"+version, "User agent sent to the API server")
+	showVersion := flag.Bool("version", false, "Print the version and exit")
+	traceRequests := flag.Bool("trace-requests", false, "Log the method, URL and response status of every request sent to the API server")
+	gvrFlag := flag.String("gvr", "", "Watch a custom resource through the dynamic client, as group/version/resource (e.g. example.com/v1/widgets), overriding -resource")
+	pageSize := flag.Int("page-size", 500, "Objects per request when listing without a streaming list (0 lists everything at once)")
+	schedulingLatency := flag.Bool("scheduling-latency", false, "Log how long each Pod took to be scheduled and then to become ready, and each PersistentVolumeClaim to be bound")
+	reconnectJitter := flag.Float64("reconnect-jitter", 0.2, "Randomly extend each reconnection delay by up to this factor (0 disables jitter)")
+	otelEndpoint := flag.String("otel-endpoint", "", "OTLP/HTTP endpoint to export watch traces to, e.g. http://localhost:4318 (tracing is disabled when empty)")
+	sinceResourceVersion := flag.String("since-resource-version", "", "Start watching from this resource version without an initial list, ignoring -state-file")
+	serverURL := flag.String("server-url", "", "Override the API server address, e.g. http://localhost:8001 for kubectl proxy or unix:///path/to/socket")
+	decodeErrorThreshold := flag.Int("decode-error-threshold", 10, "Restart the watch after this many objects failed to decode within a minute (0 never restarts)")
+	watchTimeout := flag.Duration("watch-timeout", 0, "Ask the API server to close each watch after this long so it is re-established (0 uses the server default)")
+	enableLeaderElection := flag.Bool("enable-leader-election", false, "Only watch while holding a Lease, so that a single replica produces output")
+	leaseName := flag.String("lease-name", "kube-api-streaming-demo", "Name of the Lease used for leader election")
+	leaseNamespace := flag.String("lease-namespace", "", "Namespace of the Lease used for leader election (defaults to -namespace)")
+	pipePath := flag.String("pipe-path", "", "Also write the event stream as NDJSON to this named pipe, created if missing, for another local process")
+	disableCompression := flag.Bool("disable-compression", false, "Do not request gzip compressed responses from the API server")
+	suppressWarnings := flag.Bool("suppress-warnings", false, "Do not log the warnings sent by the API server, e.g. deprecation notices")
+	namespaceLabelSelector := flag.String("namespace-label-selector", "", "Watch every namespace matching this label selector, e.g. environment=prod, overriding -namespace")
+	createKind := flag.Bool("create-kind-cluster", false, "Create a Kind cluster, unless it exists, and watch it")
+	deleteKind := flag.Bool("delete-kind-cluster", false, "Delete the Kind cluster and exit")
+	kindClusterName := flag.String("kind-cluster-name", "kube-api-streaming-demo", "Name of the Kind cluster created or deleted")
+	heartbeatInterval := flag.Duration("heartbeat-interval", 30*time.Second, "Log that the watch is still alive after this long without events (0 disables it)")
+	rvMatch := flag.String("rv-match", "", "Serve the initial list at -since-resource-version: NotOlderThan or, with -watch-list-client=false, Exact (empty skips the initial list)")
+	readyFilter := flag.String("ready-filter", "all", "Only show Pods that are ready or not ready (ready, not-ready, all)")
+	pushgatewayURL := flag.String("pushgateway-url", "", "Push the final event counters to this Prometheus Pushgateway on shutdown, e.g. http://localhost:9091")
+	listOnly := flag.Bool("list-only", false, "Print the initial list received through the streaming list, then exit without watching")
+	templateFlag := flag.String("template", "", "Go text/template executed against the object of each event, e.g. '{{.Name}} {{.Status.Phase}}', replacing the text output")
+	var onPhase repeatedFlag
+	flag.Var(&onPhase, "on-phase", "Run a command when a Pod transitions into a phase, as phase:command, e.g. Running:notify-send.sh (repeatable)")
+	cacheResync := flag.Duration("cache-resync", 0, "List periodically and drop the cached objects whose Delete event was missed (0 disables it)")
+	var contexts repeatedFlag
+	flag.Var(&contexts, "context", "Kubeconfig context to watch instead of the current one; repeat it to watch several clusters concurrently")
+	rateHalfLife := flag.Duration("rate-half-life", 10*time.Second, "Half-life of the events-per-second average shown in heartbeats and summaries (0 disables it)")
+	grpcAddr := flag.String("grpc-addr", "", "Address serving the EventStream gRPC service streaming the watch events, e.g. :9000 (empty disables it)")
+	onlyNew := flag.Bool("only-new", false, "Do not show the objects of the initial list, only the changes received after it")
+	bookmarks := flag.Bool("bookmarks", true, "Request bookmark events (allowWatchBookmarks); disabling them also disables the streaming list")
+	benchmark := flag.Bool("benchmark", false, "Measure the event throughput and the processing time of the handlers, printing a report on exit")
+	fromPod := flag.String("from-pod", "", "Watch the Pods of the same workload as this Pod, deriving the label selector from its controller or app label")
+	logFormat := flag.String("log-format", "text", "Log output format (text, json)")
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(version)
+		return
+	}
+
+	// Each of these replaces the event output, only one may be chosen
+	var outputModes []string
+	if *summary {
+		outputModes = append(outputModes, "-summary")
+	}
+	if *debounce > 0 {
+		outputModes = append(outputModes, "-debounce")
+	}
+	if *templateFlag != "" {
+		outputModes = append(outputModes, "-template")
+	}
+	if *output != "text" {
+		outputModes = append(outputModes, "-output")
+	}
+	if len(outputModes) > 1 {
+		fmt.Fprintf(os.Stderr, "%s cannot be combined\n", strings.Join(outputModes, " and "))
+		os.Exit(1)
+	}
+
+	// Keep stdout clean for the event stream in machine-readable modes
+	var handler watcher.EventHandler
+	logOut := os.Stdout
+	switch *output {
+	case "text":
+	case "ndjson":
+		handler = watcher.NewOutputHandler(watcher.NewNDJSONOutput(os.Stdout))
+		logOut = os.Stderr
+	case "table":
+		handler = watcher.NewOutputHandler(watcher.NewTableOutput(os.Stdout))
+		logOut = os.Stderr
+	default:
+		fmt.Fprintf(os.Stderr, "Unsupported output format %q\n", *output)
+		os.Exit(1)
+	}
+
+	// Parse the output template once to fail before watching
+	var outputTemplate *template.Template
+	if *templateFlag != "" {
+		var err error
+		outputTemplate, err = template.New("output").Parse(*templateFlag)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Invalid template: %v\n", err)
+			os.Exit(1)
+		}
+		logOut = os.Stderr
+	}
+
+	color, err := useColor(logOut, *colorMode)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+	logger, err := newLogger(logOut, *logFormat, color)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create logger: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Manage the local demo cluster
+	if *deleteKind {
+		if err := deleteKindCluster(context.Background(), logger, *kindClusterName); err != nil {
+			logger.Error("Failed to delete Kind cluster", "error", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if *createKind {
+		if *kubeconfig != "" || *server != "" || *inCluster || *replay != "" {
+			logger.Error("-create-kind-cluster cannot be combined with -kubeconfig, -server, -in-cluster or -replay")
+			os.Exit(1)
+		}
+		*kubeconfig, err = createKindCluster(context.Background(), logger, *kindClusterName)
+		if err != nil {
+			logger.Error("Failed to create Kind cluster", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	if outputTemplate != nil {
+		handler = watcher.NewOutputHandler(watcher.NewTemplateOutput(logger, os.Stdout, outputTemplate))
+	}
+
+	// Validate the label selector before connecting
+	if _, err := labels.Parse(*selector); err != nil {
+		logger.Error("Invalid label selector", "selector", *selector, "error", err)
+		os.Exit(1)
+	}
+
+	// Validate the field selector before connecting
+	if _, err := fields.ParseSelector(*fieldSelector); err != nil {
+		logger.Error("Invalid field selector", "field_selector", *fieldSelector, "error", err)
+		os.Exit(1)
+	}
+
+	// Validate the owner before connecting
+	var ownerKind, ownerName string
+	if *owner != "" {
+		var ok bool
+		ownerKind, ownerName, ok = strings.Cut(*owner, "/")
+		if !ok || ownerKind == "" || ownerName == "" {
+			logger.Error("Invalid owner, expected kind/name", "owner", *owner)
+			os.Exit(1)
+		}
+	}
+
+	// Replaying cannot look anything up in a cluster
+	if *replay != "" && (*owner != "" || *tailLogs) {
+		logger.Error("-replay cannot be combined with -owner or -tail-logs")
+		os.Exit(1)
+	}
+
+	if *fromPod != "" && (*replay != "" || *selector != "") {
+		logger.Error("-from-pod cannot be combined with -replay or -selector")
+		os.Exit(1)
+	}
+
+	if *listOnly && (*mode != "watch" || *replay != "") {
+		logger.Error("-list-only requires -mode watch and cannot be combined with -replay")
+		os.Exit(1)
+	}
+
+	if *cacheResync > 0 && *mode != "watch" {
+		logger.Error("-cache-resync requires -mode watch, informers relist on their own")
+		os.Exit(1)
+	}
+
+	if !*bookmarks && *mode != "watch" {
+		logger.Error("-bookmarks=false requires -mode watch")
+		os.Exit(1)
+	}
+
+	// Validate the phase hooks before connecting
+	phaseCommands := make(map[v1.PodPhase][]string)
+	for _, hook := range onPhase {
+		phase, command, ok := strings.Cut(hook, ":")
+		if !ok || phase == "" || strings.TrimSpace(command) == "" {
+			logger.Error("Invalid phase hook, expected phase:command", "on_phase", hook)
+			os.Exit(1)
+		}
+		phaseCommands[v1.PodPhase(phase)] = append(phaseCommands[v1.PodPhase(phase)], command)
+	}
+
+	// Contexts come from the kubeconfig, and some features need one cluster
+	if len(contexts) > 0 && (*replay != "" || *inCluster || *server != "") {
+		logger.Error("-context cannot be combined with -replay, -in-cluster or -server")
+		os.Exit(1)
+	}
+	if len(contexts) > 1 && (*tailLogs || *enableLeaderElection || *namespaceLabelSelector != "") {
+		logger.Error("Several -context flags cannot be combined with -tail-logs, -enable-leader-election or -namespace-label-selector")
+		os.Exit(1)
+	}
+
+	// Validate the custom resource before connecting
+	var gvr schema.GroupVersionResource
+	if *gvrFlag != "" {
+		var err error
+		gvr, err = parseGVR(*gvrFlag)
+		if err != nil {
+			logger.Error("Invalid custom resource", "gvr", *gvrFlag, "error", err)
+			os.Exit(1)
+		}
+		if *replay != "" || *mode != "watch" {
+			logger.Error("-gvr requires -mode watch and cannot be combined with -replay")
+			os.Exit(1)
+		}
+	}
+
+	// Validate the starting resource version before connecting
+	if *sinceResourceVersion != "" {
+		if _, err := strconv.ParseUint(*sinceResourceVersion, 10, 64); err != nil {
+			logger.Error("Invalid resource version, expected a number", "since_resource_version", *sinceResourceVersion)
+			os.Exit(1)
+		}
+	}
+
+	// Validate the event type before connecting
+	if *eventType != "all" && *eventType != "Warning" && *eventType != "Normal" {
+		logger.Error("Invalid event type, expected Warning, Normal or all", "event_type", *eventType)
+		os.Exit(1)
+	}
+	if *eventType == "all" {
+		*eventType = ""
+	}
+
+	// Validate the readiness filter before connecting
+	if *readyFilter != "all" && *readyFilter != watcher.ReadyFilterReady && *readyFilter != watcher.ReadyFilterNotReady {
+		logger.Error("Invalid ready filter, expected ready, not-ready or all", "ready_filter", *readyFilter)
+		os.Exit(1)
+	}
+	if *readyFilter == "all" {
+		*readyFilter = ""
+	}
+
+	// Default to the namespace of the current context like kubectl does
+	if *namespace == "" {
+		firstContext := ""
+		if len(contexts) > 0 {
+			firstContext = contexts[0]
+		}
+		*namespace = contextNamespace(logger, *kubeconfig, firstContext)
+	}
+
+	// Validate the namespace list before connecting
+	namespaceList := []string{*namespace}
+	if *namespaces != "" {
+		if *allNamespaces {
+			logger.Error("-namespaces cannot be combined with -all-namespaces")
+			os.Exit(1)
+		}
+		namespaceList = nil
+		for _, ns := range strings.Split(*namespaces, ",") {
+			if ns = strings.TrimSpace(ns); ns != "" {
+				namespaceList = append(namespaceList, ns)
+			}
+		}
+		if len(namespaceList) == 0 {
+			logger.Error("Invalid namespace list, expected at least one namespace", "namespaces", *namespaces)
+			os.Exit(1)
+		}
+	}
+
+	if *namespaceLabelSelector != "" && (*namespaces != "" || *allNamespaces || *replay != "") {
+		logger.Error("-namespace-label-selector cannot be combined with -namespaces, -all-namespaces or -replay")
+		os.Exit(1)
+	}
+
 	// Enable the WatchListClient feature gate
 	featureGate := featuregate.NewFeatureGate()
 	// Register the WatchListClient feature gate
-	err := featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
+	err = featureGate.Add(map[featuregate.Feature]featuregate.FeatureSpec{
 		"WatchListClient": {Default: true}, // The feature is enabled by default in 1.32
 	})
 	if err != nil {
-		fmt.Printf("Failed to add feature gate: %v\n", err)
-		return
+		logger.Error("Failed to add feature gate", "error", err)
+		os.Exit(1)
 	}
 
-	// Enable the feature
+	// Enable or disable the feature as requested
 	err = featureGate.SetFromMap(map[string]bool{
-		"WatchListClient": true,
+		"WatchListClient": *watchListClient,
 	})
 	if err != nil {
-		fmt.Printf("Failed to set feature gates: %v\n", err)
-		return
+		logger.Error("Failed to set feature gates", "error", err)
+		os.Exit(1)
 	}
 
-	// Verify feature gate is enabled
-	fmt.Printf("WatchListClient feature gate enabled: %v\n", featureGate.Enabled("WatchListClient"))
+	// Report which code path the feature gate selects
+	streamingList := featureGate.Enabled("WatchListClient")
+	if streamingList {
+		logger.Info("WatchListClient feature gate enabled, using streaming list (watch with sendInitialEvents)")
+	} else {
+		logger.Info("WatchListClient feature gate disabled, using classic list (LIST followed by WATCH)")
+	}
 
-	// Get the default kubeconfig path for Kind
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		fmt.Printf("Failed to get home directory: %v\n", err)
-		return
+	// Connect to each selected context, or once to the configured cluster.
+	// Replaying recorded events needs no cluster.
+	clusters := []cluster{{logger: logger}}
+	if *replay == "" {
+		contextNames := []string(contexts)
+		if len(contextNames) == 0 {
+			contextNames = []string{""}
+		}
+		clusters = clusters[:0]
+		for _, contextName := range contextNames {
+			clusterLogger := logger
+			if contextName != "" {
+				// Prefix the output of each cluster with its context
+				clusterLogger = logger.With("context", contextName)
+			}
+			clientset, dynamicClient, err := newClients(clusterLogger, connectionOptions{
+				kubeconfig:            *kubeconfig,
+				inCluster:             *inCluster,
+				context:               contextName,
+				server:                *server,
+				token:                 *token,
+				tokenFile:             *tokenFile,
+				caCert:                *caCert,
+				clientCert:            *clientCert,
+				clientKey:             *clientKey,
+				insecureSkipTLSVerify: *insecureSkipTLSVerify,
+				qps:                   *qps,
+				burst:                 *burst,
+				protobuf:              *protobuf,
+				userAgent:             *userAgent,
+				traceRequests:         *traceRequests,
+				serverURL:             *serverURL,
+				disableCompression:    *disableCompression,
+				suppressWarnings:      *suppressWarnings,
+			})
+			if err != nil {
+				clusterLogger.Error("Failed to create clientset", "error", err)
+				os.Exit(1)
+			}
+
+			// Report which version's watch semantics are exercised
+			serverVersion, err := clientset.Discovery().ServerVersion()
+
+			// Only check connectivity when doing a dry run
+			if *dryRun {
+				if err != nil {
+					clusterLogger.Error("Failed to reach the API server", "error", err)
+					os.Exit(1)
+				}
+				clusterLogger.Info("Dry run succeeded, not starting the watch", "server_version", serverVersion.GitVersion)
+				continue
+			}
+
+			connected := "Connected to cluster successfully"
+			if *createKind {
+				connected = "Connected to Kind cluster successfully"
+			}
+			if err != nil {
+				// Discovery may be forbidden without affecting the watch
+				clusterLogger.Warn("Failed to get the server version", "error", err)
+				clusterLogger.Info(connected)
+			} else {
+				clusterLogger.Info(connected, "server_version", serverVersion.GitVersion, "platform", serverVersion.Platform, "git_commit", serverVersion.GitCommit)
+			}
+			clusters = append(clusters, cluster{name: contextName, clientset: clientset, dynamic: dynamicClient, logger: clusterLogger})
+		}
+		if *dryRun {
+			return
+		}
 	}
-	kubeconfig := filepath.Join(homeDir, ".kube", "config")
+	// Features limited to a single cluster use the first one
+	clientset := clusters[0].clientset
 
-	// Create the client config
-	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
-	if err != nil {
-		fmt.Printf("Failed to create config: %v\n", err)
-		return
+	if *allNamespaces {
+		namespaceList = []string{metav1.NamespaceAll}
 	}
 
-	clientset, err := kubernetes.NewForConfig(config)
-	if err != nil {
-		fmt.Printf("Failed to create clientset: %v\n", err)
-		return
+	// Stop watching when the process is interrupted or terminated
+	started := time.Now()
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Bound the whole run when a timeout is requested
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
 	}
 
-	fmt.Println("Connected to Kind cluster successfully")
-	listPodsUsingWatch(clientset, "default")
-}
+	// Resolve the namespaces to watch from their labels
+	if *namespaceLabelSelector != "" {
+		namespaceList, err = matchingNamespaces(ctx, clientset, *namespaceLabelSelector)
+		if err != nil {
+			logger.Error("Failed to list namespaces", "selector", *namespaceLabelSelector, "error", err)
+			os.Exit(1)
+		}
+		if len(namespaceList) == 0 {
+			logger.Error("No namespace matches the label selector", "selector", *namespaceLabelSelector)
+			os.Exit(1)
+		}
+		logger.Info("Watching namespaces matching the label selector", "selector", *namespaceLabelSelector, "namespaces", namespaceList)
+	}
 
-func listPodsUsingWatch(clientset *kubernetes.Clientset, namespace string) {
-	fmt.Printf("Starting to watch pods in namespace: %s\n", namespace)
+	// Watch the siblings of a Pod
+	if *fromPod != "" {
+		if len(namespaceList) != 1 || namespaceList[0] == metav1.NamespaceAll {
+			logger.Error("-from-pod requires a single namespace")
+			os.Exit(1)
+		}
+		*selector, err = podSelector(ctx, clientset, namespaceList[0], *fromPod)
+		if err != nil {
+			logger.Error("Failed to derive a selector from the Pod", "pod_name", *fromPod, "namespace", namespaceList[0], "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Watching the siblings of the Pod", "pod_name", *fromPod, "namespace", namespaceList[0], "selector", *selector)
+	}
 
-	// Create a watch on pods with sendInitialEvents=true
-	watchOptions := metav1.ListOptions{
-		SendInitialEvents:    pointer.Bool(true), // Request the initial list via watch
-		ResourceVersionMatch: "NotOlderThan",
-		AllowWatchBookmarks:  true, // Enable bookmark events
+	// Export traces of the watch sessions
+	shutdownTracing := func(context.Context) error { return nil }
+	if *otelEndpoint != "" {
+		shutdownTracing, err = setupTracing(ctx, *otelEndpoint)
+		if err != nil {
+			logger.Error("Failed to set up tracing", "error", err)
+			os.Exit(1)
+		}
+		logger.Info("Exporting traces", "endpoint", *otelEndpoint)
 	}
-	fmt.Printf("Watch options: %+v\n", watchOptions)
 
-	watcher, err := clientset.CoreV1().Pods(namespace).Watch(context.Background(), watchOptions)
-	if err != nil {
-		fmt.Printf("Error creating watcher: %v\n", err)
-		return
+	// Expose the watch metrics for scraping
+	if *metricsAddr != "" {
+		if err := watcher.RegisterMetrics(prometheus.DefaultRegisterer); err != nil {
+			logger.Error("Failed to register metrics", "error", err)
+			os.Exit(1)
+		}
+		go serveMetrics(ctx, logger, *metricsAddr)
+	}
+
+	// Estimate the current event rate for the periodic logs
+	var eventRate *watcher.EventRate
+	if *rateHalfLife > 0 {
+		eventRate = watcher.NewEventRate(*rateHalfLife)
+	}
+
+	// Replace the event log with periodic phase summaries
+	if *summary {
+		summaryHandler := watcher.NewSummaryHandler(logger, eventRate)
+		handler = summaryHandler
+		go summaryHandler.Run(ctx, *summaryInterval)
+	}
+
+	// Aggregate bursts of events, e.g. during rollouts
+	var debouncer *watcher.DebounceHandler
+	if *debounce > 0 {
+		debouncer = watcher.NewDebounceHandler(logger, *debounce)
+		handler = debouncer
+	}
+
+	// Throttle the output of all watchers together
+	var rateLimiter *watcher.RateLimiter
+	if *maxEventsPerSecond > 0 {
+		rateLimiter = watcher.NewRateLimiter(logger, *maxEventsPerSecond)
+		go rateLimiter.Run(ctx, suppressedReportInterval)
+	}
+
+	var extraHandlers []watcher.EventHandler
+	if eventRate != nil {
+		extraHandlers = append(extraHandlers, eventRate)
+	}
+
+	// Notify a webhook about deleted Pods
+	if *webhookURL != "" {
+		notifier := watcher.NewWebhookNotifier(logger, *webhookURL)
+		extraHandlers = append(extraHandlers, notifier)
+		go notifier.Run(ctx)
+	}
+
+	// Record the event stream for a later replay
+	if *record != "" {
+		recordFile, err := os.Create(*record)
+		if err != nil {
+			logger.Error("Failed to create record file", "path", *record, "error", err)
+			os.Exit(1)
+		}
+		defer recordFile.Close()
+		extraHandlers = append(extraHandlers, watcher.NewOutputHandler(watcher.NewNDJSONOutput(recordFile)))
+	}
+
+	// Stream the events to another local process
+	if *pipePath != "" {
+		pipe, err := newPipeWriter(logger, *pipePath)
+		if err != nil {
+			logger.Error("Failed to create pipe", "path", *pipePath, "error", err)
+			os.Exit(1)
+		}
+		defer pipe.Close()
+		extraHandlers = append(extraHandlers, watcher.NewOutputHandler(watcher.NewNDJSONOutput(pipe)))
+	}
+
+	// Stream the events to gRPC clients
+	if *grpcAddr != "" {
+		broadcaster := watcher.NewBroadcaster(logger, grpcSubscriberBuffer)
+		extraHandlers = append(extraHandlers, watcher.NewOutputHandler(broadcaster))
+		go serveGRPC(ctx, logger, *grpcAddr, broadcaster)
+	}
+
+	// Stop once enough events have been processed
+	var eventLimit *watcher.EventLimit
+	if *maxEvents > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		eventLimit = watcher.NewEventLimit(logger, *maxEvents, cancel)
+		extraHandlers = append(extraHandlers, eventLimit)
 	}
-	defer watcher.Stop()
 
-	// Process the watch events
-	for event := range watcher.ResultChan() {
-		fmt.Printf("Received event type: %s\n", event.Type)
+	// Show that a quiet watch is still alive
+	if *heartbeatInterval > 0 {
+		heartbeat := watcher.NewHeartbeat(logger, *heartbeatInterval, eventRate)
+		extraHandlers = append(extraHandlers, heartbeat)
+		go heartbeat.Run(ctx)
+	}
+
+	// Profile how fast Pods are scheduled and become ready
+	if *schedulingLatency {
+		extraHandlers = append(extraHandlers, watcher.NewLatencyTracker(logger))
+	}
+
+	// Run the commands hooked to Pod phases
+	if len(phaseCommands) > 0 {
+		extraHandlers = append(extraHandlers, watcher.NewPhaseHook(ctx, logger, phaseCommands))
+	}
+
+	// Follow the logs of Pods as they become ready
+	if *tailLogs {
+		extraHandlers = append(extraHandlers, watcher.NewLogTailer(ctx, clientset, logger, logOut))
+	}
+
+	// Count the events of all watchers for the final report
+	stats := watcher.NewStats()
+
+	// Time the handlers of all watchers for the benchmark report
+	var bench *watcher.Benchmark
+	if *benchmark {
+		bench = watcher.NewBenchmark()
+	}
+
+	// Keep the current Pods for state dumps
+	pods := watcher.NewPodTracker()
+	extraHandlers = append(extraHandlers, watcher.NewOutputHandler(pods))
+
+	// Watch each requested resource in each namespace concurrently
+	resourceList := []string{*resource}
+	if *resources != "" {
+		resourceList = strings.Split(*resources, ",")
+	}
+	if *gvrFlag != "" {
+		resourceList = []string{gvr.Resource}
+	}
+	for i := range resourceList {
+		resourceList[i] = strings.TrimSpace(resourceList[i])
+	}
+
+	// Show Events about watched Pods inline when watching both
+	if slices.Contains(resourceList, "pods") && slices.Contains(resourceList, "events") {
+		extraHandlers = append(extraHandlers, watcher.NewPodEventCorrelator(logger))
+	}
+
+	watchers := make([]runner, 0, len(clusters)*len(resourceList)*len(namespaceList))
+	for _, c := range clusters {
+		for _, r := range resourceList {
+			for i, ns := range namespaceList {
+				// Cluster-scoped resources are watched once for all namespaces
+				if i > 0 && watcher.ClusterScoped(r) {
+					break
+				}
+
+				// Keep the saved resource versions of different watches apart
+				watchStateFile := *stateFile
+				if watchStateFile != "" && len(clusters) > 1 {
+					watchStateFile += "." + c.name
+				}
+				if watchStateFile != "" && len(resourceList) > 1 {
+					watchStateFile += "." + r
+				}
+				if watchStateFile != "" && len(namespaceList) > 1 && !watcher.ClusterScoped(r) {
+					watchStateFile += "." + ns
+				}
+
+				options := watcher.Options{
+					Resource:                r,
+					LabelSelector:           *selector,
+					FieldSelector:           *fieldSelector,
+					EventType:               *eventType,
+					StateFile:               watchStateFile,
+					SinceResourceVersion:    *sinceResourceVersion,
+					ResourceVersionMatch:    metav1.ResourceVersionMatch(*rvMatch),
+					Verbose:                 *verbose,
+					WatchRetries:            *watchRetries,
+					ClassicList:             !streamingList,
+					BufferInitial:           *bufferInitial,
+					OnlyNew:                 *onlyNew,
+					DisableBookmarks:        !*bookmarks,
+					Benchmark:               bench,
+					ListOnly:                *listOnly,
+					CacheResync:             *cacheResync,
+					WatchTimeout:            *watchTimeout,
+					BookmarkTimeout:         *bookmarkTimeout,
+					PageSize:                *pageSize,
+					DecodeErrorThreshold:    *decodeErrorThreshold,
+					ReconnectJitter:         *reconnectJitter,
+					InitialEventsAnnotation: *initialEventsAnnotation,
+					OnlyPhaseChanges:        *onlyPhaseChanges,
+					ReadyFilter:             *readyFilter,
+					RateLimiter:             rateLimiter,
+					Stats:                   stats,
+					Handler:                 handler,
+					OwnerKind:               ownerKind,
+					OwnerName:               ownerName,
+					ExtraHandlers:           extraHandlers,
+					Logger:                  c.logger,
+				}
+				var w runner
+				switch {
+				case *gvrFlag != "":
+					w, err = watcher.NewDynamic(c.dynamic, gvr, ns, options)
+				case *replay != "":
+					w, err = watcher.NewReplayer(*replay, ns, options)
+				case *mode == "watch":
+					w, err = watcher.New(c.clientset, ns, options)
+				case *mode == "informer":
+					w, err = watcher.NewInformer(c.clientset, ns, options)
+				default:
+					err = fmt.Errorf("unsupported mode %q", *mode)
+				}
+				if err != nil {
+					c.logger.Error("Failed to create watcher", "error", err)
+					os.Exit(1)
+				}
 
-		// Handle bookmark events separately
-		if event.Type == watch.Bookmark {
-			fmt.Printf("Received bookmark event\n")
-			if pod, ok := event.Object.(*v1.Pod); ok {
-				annotations := pod.GetAnnotations()
-				fmt.Printf("Bookmark annotations: %+v\n", annotations)
-				if annotations != nil && annotations["k8s.io/initial-events-end"] == "true" {
-					fmt.Println("Initial pod list complete, now watching for changes")
+				// Fail early with a clear message when RBAC forbids the watch
+				if c.clientset != nil && *gvrFlag == "" {
+					allowed, reason, err := watcher.CanWatch(ctx, c.clientset, r, ns)
+					if err != nil {
+						c.logger.Warn("Failed to check watch permission", "resource", r, "namespace", ns, "error", err)
+					} else if !allowed {
+						scope := "namespace " + ns
+						if ns == metav1.NamespaceAll || watcher.ClusterScoped(r) {
+							scope = "the cluster"
+						}
+						c.logger.Error(fmt.Sprintf("You don't have permission to watch %s in %s", r, scope), "reason", reason)
+						os.Exit(1)
+					}
 				}
+				watchers = append(watchers, w)
 			}
-			continue
 		}
+	}
 
-		// Handle pod events
-		pod, ok := event.Object.(*v1.Pod)
-		if !ok {
-			fmt.Printf("Received non-pod object of type %T\n", event.Object)
-			continue
+	states := func() []watcher.State {
+		states := make([]watcher.State, 0, len(watchers))
+		for _, w := range watchers {
+			states = append(states, w.State())
 		}
+		return states
+	}
+
+	// Report readiness once the initial lists have been received
+	if *healthAddr != "" {
+		go serveHealth(ctx, logger, *healthAddr, func() bool {
+			for _, w := range watchers {
+				if !w.Synced() {
+					return false
+				}
+			}
+			return true
+		}, states)
+	}
 
-		// Process the pod based on the event type
-		switch event.Type {
-		case watch.Added:
-			fmt.Printf("Pod added: %s (Phase: %s)\n", pod.Name, pod.Status.Phase)
-		case watch.Modified:
-			fmt.Printf("Pod modified: %s (Phase: %s)\n", pod.Name, pod.Status.Phase)
-		case watch.Deleted:
-			fmt.Printf("Pod deleted: %s\n", pod.Name)
-		case watch.Error:
-			fmt.Printf("Error event received: %v\n", event.Object)
-		default:
-			fmt.Printf("Unknown event type: %s for pod: %s\n", event.Type, pod.Name)
+	// Dump the state on SIGUSR1 without stopping
+	go dumpOnSignal(ctx, logger, os.Stderr, states, pods, stats)
+
+	if *enableLeaderElection && clientset != nil {
+		// Only the leader watches, the other replicas wait idle
+		lockNamespace := *leaseNamespace
+		if lockNamespace == "" {
+			lockNamespace = *namespace
+		}
+		err = runAsLeader(ctx, logger, clientset, *leaseName, lockNamespace, func(ctx context.Context) error {
+			return runWatchers(ctx, watchers)
+		})
+	} else {
+		err = runWatchers(ctx, watchers)
+	}
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		logger.Info("Timeout reached", "timeout", *timeout)
+	}
+	if debouncer != nil {
+		debouncer.Flush()
+	}
+	processed := 0
+	for _, w := range watchers {
+		processed += w.Processed()
+	}
+	logger.Info("Shutting down", "processed_events", processed)
+	if err := stats.WriteTable(logOut); err != nil {
+		logger.Warn("Failed to write event stats", "error", err)
+	}
+	if bench != nil {
+		if err := bench.WriteReport(logOut); err != nil {
+			logger.Warn("Failed to write benchmark report", "error", err)
 		}
 	}
+
+	// Flush the remaining spans, the run context may already be cancelled
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Warn("Failed to flush traces", "error", err)
+	}
+	cancel()
+
+	// Hand the counters of a short run to the Pushgateway, which does not
+	// affect the exit code
+	if *pushgatewayURL != "" {
+		pushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		if err := pushMetrics(pushCtx, *pushgatewayURL, time.Since(started)); err != nil {
+			logger.Warn("Failed to push metrics", "url", *pushgatewayURL, "error", err)
+		} else {
+			logger.Info("Pushed metrics", "url", *pushgatewayURL)
+		}
+		cancel()
+	}
+	if errors.Is(err, watcher.ErrPermissionDenied) {
+		logger.Error("The API server rejected the credentials or RBAC forbids the watch, check the Role bindings of the user")
+	}
+	if err != nil {
+		logger.Error("Watch failed", "error", err)
+		stop()
+		os.Exit(1)
+	}
+}
+
+// matchingNamespaces returns the names of the namespaces matching the label
+// selector.
+func matchingNamespaces(ctx context.Context, clientset kubernetes.Interface, selector string) ([]string, error) {
+	list, err := clientset.CoreV1().Namespaces().List(ctx, metav1.ListOptions{LabelSelector: selector})
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(list.Items))
+	for _, ns := range list.Items {
+		names = append(names, ns.Name)
+	}
+	return names, nil
+}
+
+// parseGVR parses a custom resource given as group/version/resource, or
+// version/resource for the core group.
+func parseGVR(value string) (schema.GroupVersionResource, error) {
+	parts := strings.Split(value, "/")
+	switch {
+	case len(parts) == 3 && parts[1] != "" && parts[2] != "":
+		return schema.GroupVersionResource{Group: parts[0], Version: parts[1], Resource: parts[2]}, nil
+	case len(parts) == 2 && parts[0] != "" && parts[1] != "":
+		return schema.GroupVersionResource{Version: parts[0], Resource: parts[1]}, nil
+	default:
+		return schema.GroupVersionResource{}, errors.New("expected group/version/resource")
+	}
+}
+
+// cluster holds the clients of one of the watched clusters.
+type cluster struct {
+	// name is the kubeconfig context, empty for the default cluster
+	name      string
+	clientset kubernetes.Interface
+	dynamic   dynamic.Interface
+	logger    *slog.Logger
+}
+
+// repeatedFlag is a flag.Value collecting every occurrence of a flag.
+type repeatedFlag []string
+
+// String implements flag.Value.
+func (f *repeatedFlag) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set implements flag.Value.
+func (f *repeatedFlag) Set(value string) error {
+	*f = append(*f, value)
+	return nil
+}
+
+// envOr returns the value of the environment variable key, or fallback when
+// it is unset or empty. Flags set explicitly still take precedence.
+func envOr(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// runner is implemented by watcher.Watcher and watcher.Informer.
+type runner interface {
+	Run(ctx context.Context) error
+	Synced() bool
+	Processed() int
+	State() watcher.State
+}
+
+// runWatchers runs all watchers concurrently until ctx is cancelled or one of
+// them fails, in which case the others are stopped as well.
+func runWatchers(ctx context.Context, watchers []runner) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(watchers))
+	for i, w := range watchers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.Run(ctx); err != nil {
+				errs[i] = err
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// newLogger returns a logger writing to out in the given format.
+func newLogger(out io.Writer, format string, color bool) (*slog.Logger, error) {
+	// Timestamp every line in UTC for consistency across log aggregation
+	options := &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey && len(groups) == 0 {
+				a.Value = slog.StringValue(a.Value.Time().UTC().Format(time.RFC3339Nano))
+			}
+			return a
+		},
+	}
+	var newHandler func(io.Writer) slog.Handler
+	switch format {
+	case "text":
+		newHandler = func(w io.Writer) slog.Handler { return slog.NewTextHandler(w, options) }
+	case "json":
+		newHandler = func(w io.Writer) slog.Handler { return slog.NewJSONHandler(w, options) }
+	default:
+		return nil, fmt.Errorf("unsupported log format %q", format)
+	}
+	if color {
+		return slog.New(newColorHandler(out, newHandler)), nil
+	}
+	return slog.New(newHandler(out)), nil
 }
@@ -0,0 +1,94 @@
+//go:build unix
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// pipeWriter writes to a named pipe for another local process to consume.
+// Writes are dropped while no reader has the pipe open, and the pipe is
+// reopened once a reader appears again.
+type pipeWriter struct {
+	logger  *slog.Logger
+	path    string
+	created bool
+
+	mu      sync.Mutex
+	file    *os.File
+	dropped int
+}
+
+// newPipeWriter returns a pipeWriter for the named pipe at path, creating it
+// if it does not exist.
+func newPipeWriter(logger *slog.Logger, path string) (*pipeWriter, error) {
+	p := &pipeWriter{logger: logger, path: path}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		if err := syscall.Mkfifo(path, 0o600); err != nil {
+			return nil, fmt.Errorf("creating named pipe %s: %w", path, err)
+		}
+		p.created = true
+	case err != nil:
+		return nil, err
+	case info.Mode()&fs.ModeNamedPipe == 0:
+		return nil, fmt.Errorf("%s exists and is not a named pipe", path)
+	}
+	return p, nil
+}
+
+// Write implements io.Writer.
+func (p *pipeWriter) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.file == nil {
+		// Opening without a reader fails with ENXIO instead of blocking
+		file, err := os.OpenFile(p.path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+		if errors.Is(err, syscall.ENXIO) {
+			p.dropped++
+			return len(b), nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		p.logger.Info("Pipe reader connected", "path", p.path, "dropped_events", p.dropped)
+		p.file = file
+		p.dropped = 0
+	}
+
+	n, err := p.file.Write(b)
+	if errors.Is(err, syscall.EPIPE) {
+		p.logger.Info("Pipe reader disconnected", "path", p.path)
+		_ = p.file.Close()
+		p.file = nil
+		p.dropped++
+		return len(b), nil
+	}
+	return n, err
+}
+
+// Close closes the pipe and removes it if it was created by newPipeWriter.
+func (p *pipeWriter) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	if p.file != nil {
+		err = p.file.Close()
+		p.file = nil
+	}
+	if p.created {
+		if removeErr := os.Remove(p.path); removeErr != nil && err == nil {
+			err = removeErr
+		}
+	}
+	return err
+}
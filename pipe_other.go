@@ -0,0 +1,26 @@
+//go:build !unix
+
+package main
+
+import (
+	"errors"
+	"log/slog"
+)
+
+// pipeWriter is not available on platforms without named pipes.
+type pipeWriter struct{}
+
+// newPipeWriter reports that named pipes are not supported.
+func newPipeWriter(logger *slog.Logger, path string) (*pipeWriter, error) {
+	return nil, errors.New("named pipes are only supported on unix")
+}
+
+// Write implements io.Writer.
+func (p *pipeWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Close does nothing.
+func (p *pipeWriter) Close() error {
+	return nil
+}
@@ -0,0 +1,137 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.4.0
+// - protoc             v27.3.0
+// source: eventstream.proto
+
+package eventstream
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.62.0 or later.
+const _ = grpc.SupportPackageIsVersion8
+
+const (
+	EventStream_StreamPodEvents_FullMethodName = "/eventstream.v1.EventStream/StreamPodEvents"
+)
+
+// EventStreamClient is the client API for EventStream service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type EventStreamClient interface {
+	StreamPodEvents(ctx context.Context, in *StreamPodEventsRequest, opts ...grpc.CallOption) (EventStream_StreamPodEventsClient, error)
+}
+
+type eventStreamClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewEventStreamClient(cc grpc.ClientConnInterface) EventStreamClient {
+	return &eventStreamClient{cc}
+}
+
+func (c *eventStreamClient) StreamPodEvents(ctx context.Context, in *StreamPodEventsRequest, opts ...grpc.CallOption) (EventStream_StreamPodEventsClient, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &EventStream_ServiceDesc.Streams[0], EventStream_StreamPodEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &eventStreamStreamPodEventsClient{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type EventStream_StreamPodEventsClient interface {
+	Recv() (*PodEvent, error)
+	grpc.ClientStream
+}
+
+type eventStreamStreamPodEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *eventStreamStreamPodEventsClient) Recv() (*PodEvent, error) {
+	m := new(PodEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// EventStreamServer is the server API for EventStream service.
+// All implementations must embed UnimplementedEventStreamServer
+// for forward compatibility
+type EventStreamServer interface {
+	StreamPodEvents(*StreamPodEventsRequest, EventStream_StreamPodEventsServer) error
+	mustEmbedUnimplementedEventStreamServer()
+}
+
+// UnimplementedEventStreamServer must be embedded to have forward compatible implementations.
+type UnimplementedEventStreamServer struct {
+}
+
+func (UnimplementedEventStreamServer) StreamPodEvents(*StreamPodEventsRequest, EventStream_StreamPodEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method StreamPodEvents not implemented")
+}
+func (UnimplementedEventStreamServer) mustEmbedUnimplementedEventStreamServer() {}
+
+// UnsafeEventStreamServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to EventStreamServer will
+// result in compilation errors.
+type UnsafeEventStreamServer interface {
+	mustEmbedUnimplementedEventStreamServer()
+}
+
+func RegisterEventStreamServer(s grpc.ServiceRegistrar, srv EventStreamServer) {
+	s.RegisterService(&EventStream_ServiceDesc, srv)
+}
+
+func _EventStream_StreamPodEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(StreamPodEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(EventStreamServer).StreamPodEvents(m, &eventStreamStreamPodEventsServer{ServerStream: stream})
+}
+
+type EventStream_StreamPodEventsServer interface {
+	Send(*PodEvent) error
+	grpc.ServerStream
+}
+
+type eventStreamStreamPodEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *eventStreamStreamPodEventsServer) Send(m *PodEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+// EventStream_ServiceDesc is the grpc.ServiceDesc for EventStream service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var EventStream_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "eventstream.v1.EventStream",
+	HandlerType: (*EventStreamServer)(nil),
+	Methods:     []grpc.MethodDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamPodEvents",
+			Handler:       _EventStream_StreamPodEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "eventstream.proto",
+}
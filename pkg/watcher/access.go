@@ -0,0 +1,33 @@
+package watcher
+
+import (
+	"context"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// CanWatch asks the API server with a SelfSubjectAccessReview whether the
+// current user may watch resource within namespace. It returns whether the
+// watch is allowed and the reason given by the authorizer, if any.
+func CanWatch(ctx context.Context, clientset kubernetes.Interface, resource, namespace string) (bool, string, error) {
+	if ClusterScoped(resource) {
+		namespace = metav1.NamespaceAll
+	}
+	review := &authorizationv1.SelfSubjectAccessReview{
+		Spec: authorizationv1.SelfSubjectAccessReviewSpec{
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Verb:      "watch",
+				Group:     resources[resource].group,
+				Resource:  resource,
+				Namespace: namespace,
+			},
+		},
+	}
+	result, err := clientset.AuthorizationV1().SelfSubjectAccessReviews().Create(ctx, review, metav1.CreateOptions{})
+	if err != nil {
+		return false, "", err
+	}
+	return result.Status.Allowed, result.Status.Reason, nil
+}
@@ -0,0 +1,131 @@
+package watcher
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Bounds of the processing time histogram of a Benchmark, whose buckets grow
+// by benchmarkBucketGrowth from benchmarkMinLatency. Quantiles are reported
+// as the upper bound of their bucket, within 10% of the exact value.
+const (
+	benchmarkMinLatency   = time.Microsecond
+	benchmarkBucketGrowth = 1.1
+	benchmarkBuckets      = 200
+)
+
+// Benchmark measures the throughput of the watchers sharing it and the time
+// their handlers take to process each event. It is only added to the
+// handler chain when requested, so it costs nothing otherwise.
+type Benchmark struct {
+	start time.Time
+
+	mu      sync.Mutex
+	count   int64
+	total   time.Duration
+	max     time.Duration
+	buckets [benchmarkBuckets + 1]int64
+}
+
+// NewBenchmark returns a Benchmark measuring the throughput from now.
+func NewBenchmark() *Benchmark {
+	return &Benchmark{start: time.Now()}
+}
+
+// Wrap returns an EventHandler forwarding to next and timing each call.
+func (b *Benchmark) Wrap(next EventHandler) EventHandler {
+	return &benchmarkHandler{benchmark: b, next: next}
+}
+
+// observe records the processing time of an event.
+func (b *Benchmark) observe(d time.Duration) {
+	bucket := 0
+	if d > benchmarkMinLatency {
+		bucket = min(int(math.Ceil(math.Log(float64(d)/float64(benchmarkMinLatency))/math.Log(benchmarkBucketGrowth))), benchmarkBuckets)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.count++
+	b.total += d
+	b.max = max(b.max, d)
+	b.buckets[bucket]++
+}
+
+// quantile returns the upper bound of the bucket holding the quantile q of
+// the processing times. The caller holds mu.
+func (b *Benchmark) quantile(q float64) time.Duration {
+	rank := int64(math.Ceil(q * float64(b.count)))
+	var seen int64
+	for i, n := range b.buckets {
+		seen += n
+		if seen >= rank {
+			if i == benchmarkBuckets {
+				return b.max
+			}
+			return min(time.Duration(float64(benchmarkMinLatency)*math.Pow(benchmarkBucketGrowth, float64(i))), b.max)
+		}
+	}
+	return b.max
+}
+
+// WriteReport writes the throughput and the processing time statistics as a
+// table to out.
+func (b *Benchmark) WriteReport(out io.Writer) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	elapsed := time.Since(b.start)
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "BENCHMARK\tVALUE\t\n")
+	fmt.Fprintf(w, "events\t%d\t\n", b.count)
+	fmt.Fprintf(w, "duration\t%s\t\n", elapsed.Round(time.Millisecond))
+	fmt.Fprintf(w, "events/sec\t%.1f\t\n", float64(b.count)/elapsed.Seconds())
+	if b.count > 0 {
+		fmt.Fprintf(w, "avg\t%s\t\n", b.total/time.Duration(b.count))
+		fmt.Fprintf(w, "p50\t%s\t\n", b.quantile(0.50))
+		fmt.Fprintf(w, "p95\t%s\t\n", b.quantile(0.95))
+		fmt.Fprintf(w, "p99\t%s\t\n", b.quantile(0.99))
+		fmt.Fprintf(w, "max\t%s\t\n", b.max)
+	}
+	return w.Flush()
+}
+
+// benchmarkHandler is an EventHandler timing the calls of the next handler.
+type benchmarkHandler struct {
+	benchmark *Benchmark
+	next      EventHandler
+}
+
+// OnAdded implements EventHandler.
+func (h *benchmarkHandler) OnAdded(obj runtime.Object) {
+	start := time.Now()
+	h.next.OnAdded(obj)
+	h.benchmark.observe(time.Since(start))
+}
+
+// OnModified implements EventHandler.
+func (h *benchmarkHandler) OnModified(obj runtime.Object) {
+	start := time.Now()
+	h.next.OnModified(obj)
+	h.benchmark.observe(time.Since(start))
+}
+
+// OnDeleted implements EventHandler.
+func (h *benchmarkHandler) OnDeleted(obj runtime.Object) {
+	start := time.Now()
+	h.next.OnDeleted(obj)
+	h.benchmark.observe(time.Since(start))
+}
+
+// OnBookmark implements EventHandler.
+func (h *benchmarkHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	start := time.Now()
+	h.next.OnBookmark(obj, initialEventsEnd)
+	h.benchmark.observe(time.Since(start))
+}
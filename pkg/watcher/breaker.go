@@ -0,0 +1,91 @@
+package watcher
+
+import (
+	"log/slog"
+	"time"
+)
+
+// circuitState is the state of a circuitBreaker.
+type circuitState string
+
+const (
+	// circuitClosed lets every call through.
+	circuitClosed circuitState = "closed"
+	// circuitOpen skips every call until the cooldown has passed.
+	circuitOpen circuitState = "open"
+	// circuitHalfOpen lets a trial call through to decide whether to close
+	// the circuit again.
+	circuitHalfOpen circuitState = "half-open"
+)
+
+// circuitBreaker stops calling a failing endpoint. After threshold
+// consecutive failures it opens for cooldown, skipping the calls, then lets
+// a single trial call through. It is not safe for concurrent use.
+type circuitBreaker struct {
+	logger    *slog.Logger
+	threshold int
+	cooldown  time.Duration
+	// now returns the current time, replaced in tests
+	now func() time.Time
+
+	state    circuitState
+	failures int
+	openedAt time.Time
+	// skipped counts the calls skipped since the circuit opened.
+	skipped int
+}
+
+// newCircuitBreaker returns a closed circuitBreaker.
+func newCircuitBreaker(logger *slog.Logger, threshold int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		logger:    logger,
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+		state:     circuitClosed,
+	}
+}
+
+// allow reports whether a call may be made now, counting it as skipped
+// otherwise.
+func (b *circuitBreaker) allow() bool {
+	if b.state == circuitOpen {
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			b.skipped++
+			return false
+		}
+		b.transition(circuitHalfOpen)
+	}
+	return true
+}
+
+// record updates the state with the outcome of a call let through by allow.
+func (b *circuitBreaker) record(err error) {
+	if err == nil {
+		b.failures = 0
+		if b.state != circuitClosed {
+			b.transition(circuitClosed)
+		}
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.transition(circuitOpen)
+	}
+}
+
+// transition switches to state and logs the change.
+func (b *circuitBreaker) transition(state circuitState) {
+	attrs := []any{"from", b.state, "to", state}
+	switch state {
+	case circuitOpen:
+		b.logger.Warn("Circuit breaker opened, skipping calls", append(attrs, "consecutive_failures", b.failures, "cooldown", b.cooldown)...)
+	case circuitHalfOpen:
+		b.logger.Info("Circuit breaker half-open, trying again", append(attrs, "skipped", b.skipped)...)
+	case circuitClosed:
+		b.logger.Info("Circuit breaker closed, calls resumed", attrs...)
+		b.skipped = 0
+	}
+	b.state = state
+}
@@ -0,0 +1,61 @@
+package watcher
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newCircuitBreaker(discardLogger(), 3, time.Minute)
+	b.now = func() time.Time { return now }
+	failure := errors.New("connection refused")
+
+	expect := func(step string, allowed bool, state circuitState) {
+		t.Helper()
+		if got := b.allow(); got != allowed {
+			t.Fatalf("%s: allow() = %t, want %t", step, got, allowed)
+		}
+		if b.state != state {
+			t.Fatalf("%s: state %s, want %s", step, b.state, state)
+		}
+	}
+
+	// Failures below the threshold keep the circuit closed
+	for range 2 {
+		expect("closed", true, circuitClosed)
+		b.record(failure)
+	}
+	expect("below the threshold", true, circuitClosed)
+	b.record(failure)
+	if b.state != circuitOpen {
+		t.Fatalf("state %s after 3 failures, want open", b.state)
+	}
+
+	// Calls are skipped during the cooldown
+	now = now.Add(30 * time.Second)
+	expect("cooling down", false, circuitOpen)
+	if b.skipped != 1 {
+		t.Errorf("skipped %d calls, want 1", b.skipped)
+	}
+
+	// A failed trial call opens the circuit again for another cooldown
+	now = now.Add(31 * time.Second)
+	expect("cooldown passed", true, circuitHalfOpen)
+	b.record(failure)
+	if b.state != circuitOpen {
+		t.Fatalf("state %s after a failed trial, want open", b.state)
+	}
+	now = now.Add(59 * time.Second)
+	expect("cooling down again", false, circuitOpen)
+
+	// A successful trial call closes it
+	now = now.Add(time.Second)
+	expect("second cooldown passed", true, circuitHalfOpen)
+	b.record(nil)
+	if b.state != circuitClosed || b.failures != 0 || b.skipped != 0 {
+		t.Fatalf("state %s with %d failures and %d skipped after a successful trial, want closed with none", b.state, b.failures, b.skipped)
+	}
+	expect("closed again", true, circuitClosed)
+}
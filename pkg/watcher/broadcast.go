@@ -0,0 +1,75 @@
+package watcher
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// Broadcaster is an Output fanning every event out to any number of
+// subscribers, e.g. the clients of a streaming API. Each subscriber has a
+// bounded buffer; events are dropped for a subscriber that falls behind
+// rather than blocking the watch or the other subscribers.
+type Broadcaster struct {
+	logger *slog.Logger
+	buffer int
+
+	mu          sync.Mutex
+	subscribers map[*subscriber]struct{}
+}
+
+// subscriber is a single consumer of a Broadcaster.
+type subscriber struct {
+	events chan Event
+	// dropped counts the events lost since the subscriber fell behind.
+	dropped int
+}
+
+// NewBroadcaster returns a Broadcaster buffering up to buffer events for
+// each subscriber.
+func NewBroadcaster(logger *slog.Logger, buffer int) *Broadcaster {
+	return &Broadcaster{
+		logger:      logger,
+		buffer:      buffer,
+		subscribers: make(map[*subscriber]struct{}),
+	}
+}
+
+// Subscribe registers a new subscriber and returns the channel delivering
+// its events along with a function to unsubscribe, which closes the channel.
+func (b *Broadcaster) Subscribe() (<-chan Event, func()) {
+	s := &subscriber{events: make(chan Event, b.buffer)}
+	b.mu.Lock()
+	b.subscribers[s] = struct{}{}
+	b.mu.Unlock()
+
+	var once sync.Once
+	return s.events, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, s)
+			b.mu.Unlock()
+			close(s.events)
+		})
+	}
+}
+
+// Emit implements Output.
+func (b *Broadcaster) Emit(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for s := range b.subscribers {
+		select {
+		case s.events <- event:
+			if s.dropped > 0 {
+				b.logger.Info("Subscriber caught up", "dropped_events", s.dropped)
+				s.dropped = 0
+			}
+		default:
+			// Never let a slow subscriber hold up the watch
+			if s.dropped == 0 {
+				b.logger.Warn("Subscriber is falling behind, dropping events", "buffer", b.buffer)
+			}
+			s.dropped++
+		}
+	}
+}
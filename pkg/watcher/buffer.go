@@ -0,0 +1,85 @@
+package watcher
+
+import (
+	"sort"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// BufferingHandler is an EventHandler that holds back the Added events of the
+// initial list until the initial-events-end bookmark arrives, then forwards
+// them to the next handler sorted by namespace and name. Later events are
+// forwarded immediately.
+type BufferingHandler struct {
+	next EventHandler
+
+	mu       sync.Mutex
+	buffered []runtime.Object
+	flushed  bool
+}
+
+// NewBufferingHandler returns a BufferingHandler forwarding to next.
+func NewBufferingHandler(next EventHandler) *BufferingHandler {
+	return &BufferingHandler{next: next}
+}
+
+// OnAdded implements EventHandler.
+func (h *BufferingHandler) OnAdded(obj runtime.Object) {
+	h.mu.Lock()
+	if !h.flushed {
+		h.buffered = append(h.buffered, obj)
+		h.mu.Unlock()
+		return
+	}
+	h.mu.Unlock()
+	h.next.OnAdded(obj)
+}
+
+// OnModified implements EventHandler.
+func (h *BufferingHandler) OnModified(obj runtime.Object) {
+	h.next.OnModified(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (h *BufferingHandler) OnDeleted(obj runtime.Object) {
+	h.next.OnDeleted(obj)
+}
+
+// OnBookmark implements EventHandler.
+func (h *BufferingHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	if initialEventsEnd {
+		h.flush()
+	}
+	h.next.OnBookmark(obj, initialEventsEnd)
+}
+
+// flush forwards the buffered initial objects in order and switches to
+// streaming.
+func (h *BufferingHandler) flush() {
+	h.mu.Lock()
+	buffered := h.buffered
+	h.buffered = nil
+	h.flushed = true
+	h.mu.Unlock()
+
+	sort.SliceStable(buffered, func(i, j int) bool {
+		return objectKey(buffered[i]) < objectKey(buffered[j])
+	})
+	for _, obj := range buffered {
+		h.next.OnAdded(obj)
+	}
+}
+
+// objectKey returns the namespace/name key of obj.
+func objectKey(obj runtime.Object) string {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return ""
+	}
+	if accessor.GetNamespace() == "" {
+		return accessor.GetName()
+	}
+	return accessor.GetNamespace() + "/" + accessor.GetName()
+}
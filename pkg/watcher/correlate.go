@@ -0,0 +1,70 @@
+package watcher
+
+import (
+	"log/slog"
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// PodEventCorrelator is an EventHandler shared by a Pod and an Event watch.
+// It remembers the phase of the watched Pods and logs the Events concerning
+// them inline with that phase, which helps explaining why a Pod is stuck.
+type PodEventCorrelator struct {
+	logger *slog.Logger
+
+	mu     sync.Mutex
+	phases map[string]v1.PodPhase
+}
+
+// NewPodEventCorrelator returns a PodEventCorrelator logging to logger.
+func NewPodEventCorrelator(logger *slog.Logger) *PodEventCorrelator {
+	return &PodEventCorrelator{
+		logger: logger,
+		phases: make(map[string]v1.PodPhase),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (c *PodEventCorrelator) OnAdded(obj runtime.Object) {
+	c.update(obj)
+}
+
+// OnModified implements EventHandler.
+func (c *PodEventCorrelator) OnModified(obj runtime.Object) {
+	c.update(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (c *PodEventCorrelator) OnDeleted(obj runtime.Object) {
+	if pod, ok := obj.(*v1.Pod); ok {
+		c.mu.Lock()
+		delete(c.phases, pod.Namespace+"/"+pod.Name)
+		c.mu.Unlock()
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (c *PodEventCorrelator) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// update records the phase of a Pod or logs an Event about a known Pod.
+func (c *PodEventCorrelator) update(obj runtime.Object) {
+	switch o := obj.(type) {
+	case *v1.Pod:
+		c.mu.Lock()
+		c.phases[o.Namespace+"/"+o.Name] = o.Status.Phase
+		c.mu.Unlock()
+	case *v1.Event:
+		if o.InvolvedObject.Kind != "Pod" {
+			return
+		}
+		c.mu.Lock()
+		phase, ok := c.phases[o.InvolvedObject.Namespace+"/"+o.InvolvedObject.Name]
+		c.mu.Unlock()
+		if !ok {
+			return
+		}
+		c.logger.Info("Pod event", "pod_name", o.InvolvedObject.Name, "namespace", o.InvolvedObject.Namespace, "phase", phase, "type", o.Type, "reason", o.Reason, "message", o.Message)
+	}
+}
@@ -0,0 +1,123 @@
+package watcher
+
+import (
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// debounced collects the events of one object within a debounce window.
+type debounced struct {
+	counts map[watch.EventType]int
+	last   runtime.Object
+	timer  *time.Timer
+}
+
+// DebounceHandler is an EventHandler aggregating bursts of events, such as
+// those of a rollout. It logs a single summary per object once no event for
+// it arrived within the window instead of logging every event.
+type DebounceHandler struct {
+	logger *slog.Logger
+	window time.Duration
+
+	mu      sync.Mutex
+	pending map[string]*debounced
+}
+
+// NewDebounceHandler returns a DebounceHandler logging to logger after window
+// passed without events for an object.
+func NewDebounceHandler(logger *slog.Logger, window time.Duration) *DebounceHandler {
+	return &DebounceHandler{
+		logger:  logger,
+		window:  window,
+		pending: make(map[string]*debounced),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (h *DebounceHandler) OnAdded(obj runtime.Object) {
+	h.add(watch.Added, obj)
+}
+
+// OnModified implements EventHandler.
+func (h *DebounceHandler) OnModified(obj runtime.Object) {
+	h.add(watch.Modified, obj)
+}
+
+// OnDeleted implements EventHandler.
+func (h *DebounceHandler) OnDeleted(obj runtime.Object) {
+	h.add(watch.Deleted, obj)
+}
+
+// OnBookmark implements EventHandler.
+func (h *DebounceHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	if initialEventsEnd {
+		h.logger.Info("Initial list complete, now watching for changes")
+	}
+}
+
+// add records an event and restarts the window of its object.
+func (h *DebounceHandler) add(eventType watch.EventType, obj runtime.Object) {
+	key := kindOf(obj) + "/" + objectKey(obj)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	entry, ok := h.pending[key]
+	if !ok {
+		entry = &debounced{counts: make(map[watch.EventType]int)}
+		entry.timer = time.AfterFunc(h.window, func() { h.flush(key) })
+		h.pending[key] = entry
+	} else {
+		entry.timer.Reset(h.window)
+	}
+	entry.counts[eventType]++
+	entry.last = obj
+}
+
+// Flush stops the pending windows and logs their summaries right away, in
+// order of kind, namespace and name. Call it before exiting so the last
+// events are not lost.
+func (h *DebounceHandler) Flush() {
+	h.mu.Lock()
+	keys := make([]string, 0, len(h.pending))
+	for key, entry := range h.pending {
+		entry.timer.Stop()
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	entries := make([]*debounced, 0, len(keys))
+	for _, key := range keys {
+		entries = append(entries, h.pending[key])
+		delete(h.pending, key)
+	}
+	h.mu.Unlock()
+
+	for _, entry := range entries {
+		h.summarize(entry)
+	}
+}
+
+// flush logs the summary of an object whose window has passed.
+func (h *DebounceHandler) flush(key string) {
+	h.mu.Lock()
+	entry, ok := h.pending[key]
+	delete(h.pending, key)
+	h.mu.Unlock()
+	if ok {
+		h.summarize(entry)
+	}
+}
+
+// summarize logs the summary of the events of an object.
+func (h *DebounceHandler) summarize(entry *debounced) {
+	attrs := []any{"kind", kindOf(entry.last), "name", objectKey(entry.last),
+		"added", entry.counts[watch.Added],
+		"modifications", entry.counts[watch.Modified],
+		"deleted", entry.counts[watch.Deleted] > 0,
+	}
+	attrs = append(attrs, describe(entry.last)...)
+	h.logger.Info("Aggregated events", attrs...)
+}
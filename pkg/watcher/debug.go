@@ -0,0 +1,74 @@
+package watcher
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// State is a snapshot of the internal state of a watch for debugging.
+type State struct {
+	Resource        string         `json:"resource"`
+	Namespace       string         `json:"namespace"`
+	ResourceVersion string         `json:"resourceVersion"`
+	Processed       int            `json:"processed"`
+	Events          map[string]int `json:"events"`
+	Reconnects      int            `json:"reconnects"`
+	Synced          bool           `json:"synced"`
+}
+
+// debugState tracks the parts of State updated by the event loop. It is safe
+// for concurrent use.
+type debugState struct {
+	mu              sync.Mutex
+	resourceVersion string
+	events          map[watch.EventType]int
+	reconnects      int
+}
+
+// observe counts an event of the given type and records its resource
+// version unless it is empty.
+func (s *debugState) observe(eventType watch.EventType, resourceVersion string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.events == nil {
+		s.events = make(map[watch.EventType]int)
+	}
+	s.events[eventType]++
+	if resourceVersion != "" {
+		s.resourceVersion = resourceVersion
+	}
+}
+
+// reconnect counts a reconnection of the watch.
+func (s *debugState) reconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.reconnects++
+}
+
+// fill copies the tracked state into state.
+func (s *debugState) fill(state *State) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	state.ResourceVersion = s.resourceVersion
+	state.Reconnects = s.reconnects
+	state.Events = make(map[string]int, len(s.events))
+	for eventType, count := range s.events {
+		state.Events[string(eventType)] = count
+	}
+}
+
+// WriteStates writes states as a table to out, in the layout of the table
+// output.
+func WriteStates(out io.Writer, states []State) error {
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "RESOURCE\tNAMESPACE\tRESOURCE VERSION\tPROCESSED\tRECONNECTS\tSYNCED")
+	for _, state := range states {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%t\n", state.Resource, state.Namespace, state.ResourceVersion, state.Processed, state.Reconnects, state.Synced)
+	}
+	return w.Flush()
+}
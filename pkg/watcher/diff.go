@@ -0,0 +1,40 @@
+package watcher
+
+import (
+	"fmt"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+// podChanges compares the key fields of two versions of a Pod and returns
+// the differences as "field: old -> new" entries.
+func podChanges(previous, current *v1.Pod) []string {
+	var changes []string
+	add := func(field string, old, new any) {
+		if old != new {
+			changes = append(changes, fmt.Sprintf("%s: %v -> %v", field, old, new))
+		}
+	}
+
+	add("phase", previous.Status.Phase, current.Status.Phase)
+	add("ready", podReady(previous), podReady(current))
+	add("node", previous.Spec.NodeName, current.Spec.NodeName)
+	add("deleting", previous.DeletionTimestamp != nil, current.DeletionTimestamp != nil)
+
+	images := make(map[string]string, len(previous.Spec.Containers))
+	for _, container := range previous.Spec.Containers {
+		images[container.Name] = container.Image
+	}
+	for _, container := range current.Spec.Containers {
+		if image, ok := images[container.Name]; ok {
+			add("image["+container.Name+"]", image, container.Image)
+		}
+	}
+	return changes
+}
+
+// formatChanges joins the changes of podChanges into a single line.
+func formatChanges(changes []string) string {
+	return strings.Join(changes, ", ")
+}
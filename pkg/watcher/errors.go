@@ -0,0 +1,42 @@
+package watcher
+
+import (
+	"errors"
+	"fmt"
+	"net"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+)
+
+// Errors returned for common failure modes, wrapping the underlying error so
+// that callers can react to them with errors.Is.
+var (
+	// ErrKubeconfigNotFound is returned when no kubeconfig file exists.
+	ErrKubeconfigNotFound = errors.New("kubeconfig not found")
+	// ErrWatchUnsupported is returned when the resource cannot be watched,
+	// e.g. because it is not served by the API server.
+	ErrWatchUnsupported = errors.New("watch not supported")
+	// ErrPermissionDenied is returned when the API server rejects the
+	// credentials or RBAC forbids the request.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrConnectionFailed is returned when the API server cannot be reached.
+	ErrConnectionFailed = errors.New("connection to the API server failed")
+)
+
+// classify wraps err with the typed error matching its API status or cause.
+// Errors that match none of them are returned unchanged.
+func classify(err error) error {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return nil
+	case apierrors.IsForbidden(err) || apierrors.IsUnauthorized(err):
+		return fmt.Errorf("%w: %w", ErrPermissionDenied, err)
+	case apierrors.IsNotFound(err) || apierrors.IsMethodNotSupported(err):
+		return fmt.Errorf("%w: %w", ErrWatchUnsupported, err)
+	case errors.Is(err, errRetriesExhausted) || apierrors.IsServiceUnavailable(err) || errors.As(err, &netErr):
+		return fmt.Errorf("%w: %w", ErrConnectionFailed, err)
+	default:
+		return err
+	}
+}
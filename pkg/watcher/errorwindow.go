@@ -0,0 +1,26 @@
+package watcher
+
+import "time"
+
+// errorWindow counts errors within a sliding time window.
+type errorWindow struct {
+	threshold int
+	window    time.Duration
+	times     []time.Time
+}
+
+// observe records an error at now and reports whether the threshold has been
+// reached within the window. A zero threshold is never reached.
+func (e *errorWindow) observe(now time.Time) bool {
+	if e.threshold <= 0 {
+		return false
+	}
+	kept := e.times[:0]
+	for _, t := range e.times {
+		if now.Sub(t) < e.window {
+			kept = append(kept, t)
+		}
+	}
+	e.times = append(kept, now)
+	return len(e.times) >= e.threshold
+}
@@ -0,0 +1,348 @@
+package watcher
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// EventHandler receives the events observed by a Watcher.
+type EventHandler interface {
+	// OnAdded is called for objects that were created or are part of the
+	// initial list.
+	OnAdded(obj runtime.Object)
+	// OnModified is called for objects that were updated.
+	OnModified(obj runtime.Object)
+	// OnDeleted is called for objects that were deleted.
+	OnDeleted(obj runtime.Object)
+	// OnBookmark is called for bookmark events. initialEventsEnd is true for
+	// the bookmark marking the end of the initial list.
+	OnBookmark(obj runtime.Object, initialEventsEnd bool)
+}
+
+// LogOptions configures a LogHandler.
+type LogOptions struct {
+	// AllNamespaces logs names as namespace/name.
+	AllNamespaces bool
+	// Verbose adds container statuses and restart counts to Pod events.
+	Verbose bool
+	// OnlyPhaseChanges suppresses Modified events of Pods whose phase did
+	// not change and logs the phase transition otherwise.
+	OnlyPhaseChanges bool
+}
+
+// LogHandler is an EventHandler that logs every event. It is also the text
+// Output, logging through slog which serializes the lines.
+type LogHandler struct {
+	logger  *slog.Logger
+	kind    string
+	nameKey string
+	options LogOptions
+
+	// phases tracks the last seen phase of each Pod for OnlyPhaseChanges.
+	mu     sync.Mutex
+	phases map[string]v1.PodPhase
+	// pods holds the last seen version of each Pod to log what changed.
+	pods map[types.UID]*v1.Pod
+}
+
+// NewLogHandler returns a LogHandler logging events for objects of the given
+// kind.
+func NewLogHandler(logger *slog.Logger, kind string, options LogOptions) *LogHandler {
+	return &LogHandler{
+		logger:  logger,
+		kind:    kind,
+		nameKey: strings.ToLower(kind) + "_name",
+		options: options,
+		phases:  make(map[string]v1.PodPhase),
+		pods:    make(map[types.UID]*v1.Pod),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (h *LogHandler) OnAdded(obj runtime.Object) {
+	h.trackPhase(obj)
+	// A relist after reconnecting adds the Pods that are already known again
+	if previous := h.trackPod(obj); previous != nil {
+		h.logger.Info(h.kind+" re-added (after relist)", h.attrs("RE-ADDED", obj)...)
+		return
+	}
+	h.logger.Info(h.kind+" added", h.attrs("ADDED", obj)...)
+}
+
+// OnModified implements EventHandler.
+func (h *LogHandler) OnModified(obj runtime.Object) {
+	attrs := h.attrs("MODIFIED", obj)
+	if previous := h.trackPod(obj); previous != nil {
+		if changes := podChanges(previous, obj.(*v1.Pod)); len(changes) > 0 {
+			attrs = append(attrs, "changes", formatChanges(changes))
+		}
+	}
+	if pod, ok := obj.(*v1.Pod); ok && h.options.OnlyPhaseChanges {
+		previous, seen := h.trackPhase(obj)
+		if seen && previous == pod.Status.Phase {
+			return
+		}
+		if seen {
+			attrs = append(attrs, "transition", fmt.Sprintf("%s -> %s", previous, pod.Status.Phase))
+		}
+	}
+	h.logger.Info(h.kind+" modified", attrs...)
+}
+
+// OnDeleted implements EventHandler.
+func (h *LogHandler) OnDeleted(obj runtime.Object) {
+	if h.options.OnlyPhaseChanges {
+		h.mu.Lock()
+		delete(h.phases, objectKey(obj))
+		h.mu.Unlock()
+	}
+	if pod, ok := obj.(*v1.Pod); ok && pod.UID != "" {
+		h.mu.Lock()
+		delete(h.pods, pod.UID)
+		h.mu.Unlock()
+	}
+	h.logger.Info(h.kind+" deleted", h.attrs("DELETED", obj)...)
+}
+
+// OnBookmark implements EventHandler.
+func (h *LogHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	h.logger.Info("Received bookmark event", "event_type", "BOOKMARK", "resource_version", accessor.GetResourceVersion(), "annotations", accessor.GetAnnotations())
+	if initialEventsEnd {
+		h.logger.Info("Initial list complete, now watching for changes")
+	}
+}
+
+// Emit implements Output, making the LogHandler the text output.
+func (h *LogHandler) Emit(event Event) {
+	switch event.Type {
+	case watch.Added:
+		h.OnAdded(event.Object)
+	case watch.Modified:
+		h.OnModified(event.Object)
+	case watch.Deleted:
+		h.OnDeleted(event.Object)
+	case watch.Bookmark:
+		h.OnBookmark(event.Object, event.InitialEventsEnd)
+	}
+}
+
+// warm implements cacheWarmer, recording an object that is not logged.
+func (h *LogHandler) warm(obj runtime.Object) {
+	h.trackPhase(obj)
+	h.trackPod(obj)
+}
+
+// trackPhase records the phase of a Pod for OnlyPhaseChanges and returns the
+// previously recorded phase, if any.
+func (h *LogHandler) trackPhase(obj runtime.Object) (v1.PodPhase, bool) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || !h.options.OnlyPhaseChanges {
+		return "", false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	key := objectKey(obj)
+	previous, seen := h.phases[key]
+	h.phases[key] = pod.Status.Phase
+	return previous, seen
+}
+
+// trackPod records the current version of a Pod and returns the previously
+// recorded version, if any. Pods without a UID, e.g. replayed from a
+// recording predating it, are not recorded since they would all share one
+// entry.
+func (h *LogHandler) trackPod(obj runtime.Object) *v1.Pod {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.UID == "" {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	previous := h.pods[pod.UID]
+	h.pods[pod.UID] = pod
+	return previous
+}
+
+// attrs returns the log attributes for an event on obj.
+func (h *LogHandler) attrs(eventType string, obj runtime.Object) []any {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return []any{"event_type", eventType}
+	}
+	attrs := []any{"event_type", eventType, h.nameKey, h.objectName(accessor), "namespace", accessor.GetNamespace(), "resource_version", accessor.GetResourceVersion()}
+	attrs = append(attrs, describe(obj)...)
+	if pod, ok := obj.(*v1.Pod); ok {
+		// Show how long after the last status change the event arrived
+		if transition := lastTransitionTime(pod); !transition.IsZero() {
+			attrs = append(attrs, "latency", time.Since(transition).Round(time.Millisecond))
+		}
+		if h.options.Verbose {
+			attrs = append(attrs, "containers", containerStatuses(pod))
+		}
+	}
+	return attrs
+}
+
+// objectName returns the name to log for obj. When watching all namespaces
+// the name is qualified as namespace/name so objects can be told apart.
+func (h *LogHandler) objectName(obj metav1.Object) string {
+	if h.options.AllNamespaces && obj.GetNamespace() != "" {
+		return obj.GetNamespace() + "/" + obj.GetName()
+	}
+	return obj.GetName()
+}
+
+// describe returns extra kind-specific log attributes for an object, such as
+// the phase of a Pod, using the first describer handling its type.
+func describe(obj runtime.Object) []any {
+	for _, d := range describers {
+		if attrs, ok := d(obj); ok {
+			return attrs
+		}
+	}
+	return nil
+}
+
+// describer returns the log attributes of an object and whether it handles
+// its type.
+type describer func(obj runtime.Object) ([]any, bool)
+
+// describeAs adapts a renderer of the typed object T into a describer, so
+// each supported type only needs a function of its own type.
+func describeAs[T runtime.Object](render func(T) []any) describer {
+	return func(obj runtime.Object) ([]any, bool) {
+		typed, ok := obj.(T)
+		if !ok {
+			return nil, false
+		}
+		return render(typed), true
+	}
+}
+
+// describers lists the renderers of the supported types.
+var describers = []describer{
+	describeAs(func(pod *v1.Pod) []any {
+		return []any{"phase", pod.Status.Phase}
+	}),
+	describeAs(func(deployment *appsv1.Deployment) []any {
+		return []any{"replicas", deployment.Status.Replicas, "ready_replicas", deployment.Status.ReadyReplicas}
+	}),
+	describeAs(jobStatus),
+	describeAs(nodeConditions),
+	describeAs(func(event *v1.Event) []any {
+		return []any{"type", event.Type, "reason", event.Reason, "involved_object", event.InvolvedObject.Kind + "/" + event.InvolvedObject.Name, "message", event.Message}
+	}),
+	describeAs(func(claim *v1.PersistentVolumeClaim) []any {
+		return []any{"phase", claim.Status.Phase, "volume", claim.Spec.VolumeName}
+	}),
+	describeAs(unstructuredStatus),
+}
+
+// unstructuredStatusFields lists the status fields commonly used by custom
+// resources to report their phase, in order of preference.
+var unstructuredStatusFields = []string{"phase", "state", "status"}
+
+// unstructuredStatus returns the kind and the first phase-like status field
+// of a custom resource as log attributes.
+func unstructuredStatus(obj *unstructured.Unstructured) []any {
+	attrs := []any{"object_kind", obj.GetKind()}
+	for _, field := range unstructuredStatusFields {
+		if value, found, err := unstructured.NestedString(obj.Object, "status", field); err == nil && found {
+			return append(attrs, field, value)
+		}
+	}
+	return attrs
+}
+
+// jobStatus returns the Pod counts of job and its terminal condition, if
+// any, as log attributes, along with its duration once it completed.
+func jobStatus(job *batchv1.Job) []any {
+	attrs := []any{"active", job.Status.Active, "succeeded", job.Status.Succeeded, "failed", job.Status.Failed}
+	for _, condition := range job.Status.Conditions {
+		if (condition.Type == batchv1.JobComplete || condition.Type == batchv1.JobFailed) && condition.Status == v1.ConditionTrue {
+			attrs = append(attrs, "condition", condition.Type)
+		}
+	}
+	if job.Status.StartTime != nil && job.Status.CompletionTime != nil {
+		attrs = append(attrs, "duration", job.Status.CompletionTime.Sub(job.Status.StartTime.Time))
+	}
+	return attrs
+}
+
+// nodeConditionKeys maps the reported Node conditions to their log keys.
+var nodeConditionKeys = []struct {
+	condition v1.NodeConditionType
+	key       string
+}{
+	{v1.NodeReady, "ready"},
+	{v1.NodeMemoryPressure, "memory_pressure"},
+	{v1.NodeDiskPressure, "disk_pressure"},
+	{v1.NodePIDPressure, "pid_pressure"},
+}
+
+// nodeConditions returns the status of the Ready and pressure conditions of
+// node as log attributes.
+func nodeConditions(node *v1.Node) []any {
+	var attrs []any
+	for _, c := range nodeConditionKeys {
+		for _, condition := range node.Status.Conditions {
+			if condition.Type == c.condition {
+				attrs = append(attrs, c.key, condition.Status)
+			}
+		}
+	}
+	return attrs
+}
+
+// lastTransitionTime returns the most recent LastTransitionTime of the
+// conditions of pod, or the zero time if none is set.
+func lastTransitionTime(pod *v1.Pod) time.Time {
+	var latest time.Time
+	for _, condition := range pod.Status.Conditions {
+		if t := condition.LastTransitionTime.Time; t.After(latest) {
+			latest = t
+		}
+	}
+	return latest
+}
+
+// containerStatuses summarizes the readiness, restart count and state of each
+// container in pod as "name: ready=<bool> restarts=<n> state=<state>".
+func containerStatuses(pod *v1.Pod) []string {
+	statuses := make([]string, 0, len(pod.Status.ContainerStatuses))
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses = append(statuses, fmt.Sprintf("%s: ready=%t restarts=%d state=%s", status.Name, status.Ready, status.RestartCount, containerState(status.State)))
+	}
+	return statuses
+}
+
+// containerState returns the name of the state a container is in.
+func containerState(state v1.ContainerState) string {
+	switch {
+	case state.Running != nil:
+		return "Running"
+	case state.Waiting != nil:
+		return "Waiting"
+	case state.Terminated != nil:
+		return "Terminated"
+	default:
+		return "Unknown"
+	}
+}
@@ -0,0 +1,80 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Heartbeat is an EventHandler logging periodically while no events, not
+// counting bookmarks, arrived, to tell a quiet watch from a broken one. It
+// may be shared between watchers.
+type Heartbeat struct {
+	logger   *slog.Logger
+	interval time.Duration
+	rate     *EventRate
+	count    atomic.Int64
+	// last holds the time of the last event in Unix nanoseconds.
+	last atomic.Int64
+}
+
+// NewHeartbeat returns a Heartbeat logging after interval without events,
+// including the current event rate if rate is not nil.
+func NewHeartbeat(logger *slog.Logger, interval time.Duration, rate *EventRate) *Heartbeat {
+	h := &Heartbeat{logger: logger, interval: interval, rate: rate}
+	h.last.Store(time.Now().UnixNano())
+	return h
+}
+
+// Run logs a heartbeat whenever interval passed without events until ctx is
+// cancelled.
+func (h *Heartbeat) Run(ctx context.Context) {
+	timer := time.NewTimer(h.interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			// Wait a full interval after the last event before logging
+			idle := time.Since(time.Unix(0, h.last.Load()))
+			if idle >= h.interval {
+				attrs := []any{"events", h.count.Load(), "last_event_ago", idle.Round(time.Second)}
+				if h.rate != nil {
+					attrs = append(attrs, "events_per_second", formatRate(h.rate.PerSecond()))
+				}
+				h.logger.Info("Still watching", attrs...)
+				timer.Reset(h.interval)
+			} else {
+				timer.Reset(h.interval - idle)
+			}
+		}
+	}
+}
+
+// OnAdded implements EventHandler.
+func (h *Heartbeat) OnAdded(obj runtime.Object) {
+	h.observe()
+}
+
+// OnModified implements EventHandler.
+func (h *Heartbeat) OnModified(obj runtime.Object) {
+	h.observe()
+}
+
+// OnDeleted implements EventHandler.
+func (h *Heartbeat) OnDeleted(obj runtime.Object) {
+	h.observe()
+}
+
+// OnBookmark implements EventHandler.
+func (h *Heartbeat) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// observe counts an event and resets the idle time.
+func (h *Heartbeat) observe() {
+	h.count.Add(1)
+	h.last.Store(time.Now().UnixNano())
+}
@@ -0,0 +1,117 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// hookTimeout bounds how long a phase hook command may run.
+const hookTimeout = 30 * time.Second
+
+// PhaseHook is an EventHandler running external commands when a Pod
+// transitions into a given phase. The commands run in the background with
+// POD_NAME, POD_NAMESPACE and POD_PHASE set in their environment.
+type PhaseHook struct {
+	ctx      context.Context
+	logger   *slog.Logger
+	commands map[v1.PodPhase][]string
+
+	mu     sync.Mutex
+	phases map[types.UID]v1.PodPhase
+}
+
+// NewPhaseHook returns a PhaseHook running the commands of each phase, given
+// as a program followed by its arguments separated by spaces. Running
+// commands are killed once ctx is cancelled.
+func NewPhaseHook(ctx context.Context, logger *slog.Logger, commands map[v1.PodPhase][]string) *PhaseHook {
+	return &PhaseHook{
+		ctx:      ctx,
+		logger:   logger,
+		commands: commands,
+		phases:   make(map[types.UID]v1.PodPhase),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (h *PhaseHook) OnAdded(obj runtime.Object) {
+	h.update(obj)
+}
+
+// OnModified implements EventHandler.
+func (h *PhaseHook) OnModified(obj runtime.Object) {
+	h.update(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (h *PhaseHook) OnDeleted(obj runtime.Object) {
+	if pod, ok := obj.(*v1.Pod); ok {
+		h.mu.Lock()
+		delete(h.phases, pod.UID)
+		h.mu.Unlock()
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (h *PhaseHook) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// update records the phase of a Pod and runs the commands of its phase when
+// it changed. The first phase seen of a Pod, e.g. in the initial list, is
+// not a transition.
+func (h *PhaseHook) update(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	previous, seen := h.phases[pod.UID]
+	h.phases[pod.UID] = pod.Status.Phase
+	h.mu.Unlock()
+	if !seen || previous == pod.Status.Phase {
+		return
+	}
+	for _, command := range h.commands[pod.Status.Phase] {
+		go h.run(command, pod)
+	}
+}
+
+// run executes command for pod and logs its exit status.
+func (h *PhaseHook) run(command string, pod *v1.Pod) {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(h.ctx, hookTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = append(os.Environ(),
+		"POD_NAME="+pod.Name,
+		"POD_NAMESPACE="+pod.Namespace,
+		"POD_PHASE="+string(pod.Status.Phase),
+	)
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	attrs := []any{"command", command, "pod_name", pod.Name, "namespace", pod.Namespace, "phase", pod.Status.Phase, "duration", time.Since(start).Round(time.Millisecond)}
+	if len(output) > 0 {
+		attrs = append(attrs, "output", strings.TrimSpace(string(output)))
+	}
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		h.logger.Info("Phase hook succeeded", append(attrs, "exit_code", 0)...)
+	case errors.As(err, &exitErr):
+		h.logger.Warn("Phase hook failed", append(attrs, "exit_code", exitErr.ExitCode(), "error", err)...)
+	default:
+		h.logger.Warn("Phase hook failed", append(attrs, "error", err)...)
+	}
+}
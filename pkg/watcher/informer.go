@@ -0,0 +1,156 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// Informer watches a resource type through a shared informer instead of a raw
+// watch, leaving relists, reconnects and caching to client-go. It reports
+// events to the same EventHandler as a Watcher.
+type Informer struct {
+	clientset kubernetes.Interface
+	namespace string
+	options   Options
+	processed atomic.Int64
+	synced    atomic.Bool
+	debug     debugState
+}
+
+// NewInformer returns an Informer for the resource in options within
+// namespace. The state file, retry and classic list options do not apply.
+// CacheResync is rejected since the informer relists on its own.
+func NewInformer(clientset kubernetes.Interface, namespace string, options Options) (*Informer, error) {
+	if options.CacheResync > 0 {
+		return nil, errors.New("cache resync is not supported by informers, which keep their cache consistent by relisting")
+	}
+	if ClusterScoped(options.Resource) {
+		namespace = metav1.NamespaceAll
+	}
+	if _, err := options.complete(clientset, namespace); err != nil {
+		return nil, err
+	}
+	return &Informer{
+		clientset: clientset,
+		namespace: namespace,
+		options:   options,
+	}, nil
+}
+
+// Processed returns the number of events received so far.
+func (i *Informer) Processed() int {
+	return int(i.processed.Load())
+}
+
+// Synced reports whether the informer cache has synced.
+func (i *Informer) Synced() bool {
+	return i.synced.Load()
+}
+
+// State returns a snapshot of the internal state of the informer.
+func (i *Informer) State() State {
+	state := State{
+		Resource:  i.options.Resource,
+		Namespace: i.namespace,
+		Processed: i.Processed(),
+		Synced:    i.Synced(),
+	}
+	i.debug.fill(&state)
+	return state
+}
+
+// Run starts the informer and dispatches its events until ctx is cancelled.
+func (i *Informer) Run(ctx context.Context) error {
+	logger := i.options.Logger
+	handler := i.options.Handler
+	logger.Info("Starting informer", "resource", i.options.Resource, "namespace", i.namespace, "selector", i.options.LabelSelector, "field_selector", i.options.FieldSelector)
+
+	if i.options.ownerFilter != nil {
+		i.options.ownerFilter.bind(ctx)
+	}
+
+	factory := informers.NewSharedInformerFactoryWithOptions(i.clientset, 0,
+		informers.WithNamespace(i.namespace),
+		informers.WithTweakListOptions(func(opts *metav1.ListOptions) {
+			opts.LabelSelector = i.options.LabelSelector
+			opts.FieldSelector = i.options.FieldSelector
+		}),
+	)
+	informer, err := informerFor(factory, i.options.Resource)
+	if err != nil {
+		return err
+	}
+
+	registration, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if o, ok := obj.(runtime.Object); ok {
+				i.observe(watch.Added, o)
+				handler.OnAdded(o)
+			}
+		},
+		UpdateFunc: func(oldObj, newObj interface{}) {
+			if o, ok := newObj.(runtime.Object); ok {
+				i.observe(watch.Modified, o)
+				handler.OnModified(o)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			// The final state is unknown when the delete was missed
+			if tombstone, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if o, ok := obj.(runtime.Object); ok {
+				i.observe(watch.Deleted, o)
+				handler.OnDeleted(o)
+			}
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("adding event handler: %w", err)
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+
+	logger.Info("Waiting for informer cache to sync")
+	// Wait for the handler to receive the initial objects, not only for the
+	// store to fill, so the bookmark below arrives after them
+	if !cache.WaitForCacheSync(ctx.Done(), registration.HasSynced) {
+		if ctx.Err() != nil {
+			return nil
+		}
+		return errors.New("informer cache failed to sync")
+	}
+	logger.Info("Informer cache synced, now watching for changes", "resource_version", informer.LastSyncResourceVersion())
+
+	// Signal the end of the initial list the same way a streaming list does
+	i.synced.Store(true)
+	recordEvent(watch.Bookmark)
+	i.options.Stats.observe(watch.Bookmark)
+	handler.OnBookmark(initialEventsEndBookmark(i.options.InitialEventsAnnotation, informer.LastSyncResourceVersion()), true)
+
+	<-ctx.Done()
+	return nil
+}
+
+// observe counts an event delivered by the informer.
+func (i *Informer) observe(eventType watch.EventType, obj runtime.Object) {
+	i.processed.Add(1)
+	var resourceVersion string
+	if accessor, err := meta.Accessor(obj); err == nil {
+		resourceVersion = accessor.GetResourceVersion()
+	}
+	recordEvent(eventType)
+	i.options.Stats.observe(eventType)
+	i.debug.observe(eventType, resourceVersion)
+}
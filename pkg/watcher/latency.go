@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// podConditions records whether the conditions of a Pod were true at its
+// last event.
+type podConditions struct {
+	scheduled bool
+	ready     bool
+}
+
+// LatencyTracker is an EventHandler logging, for each Pod, the time from its
+// creation until it was scheduled and from being scheduled until it became
+// ready, when the respective condition turns true during the watch. Pods
+// whose conditions are already true when first seen, e.g. in the initial
+// list, are not reported. For each
+// PersistentVolumeClaim seen pending it logs the time from its creation until
+// it was observed bound.
+type LatencyTracker struct {
+	logger *slog.Logger
+
+	mu   sync.Mutex
+	pods map[types.UID]*podConditions
+	// pending holds the claims waiting to be bound.
+	pending map[types.UID]bool
+}
+
+// NewLatencyTracker returns a LatencyTracker logging to logger.
+func NewLatencyTracker(logger *slog.Logger) *LatencyTracker {
+	return &LatencyTracker{
+		logger:  logger,
+		pods:    make(map[types.UID]*podConditions),
+		pending: make(map[types.UID]bool),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (t *LatencyTracker) OnAdded(obj runtime.Object) {
+	t.update(obj)
+}
+
+// OnModified implements EventHandler.
+func (t *LatencyTracker) OnModified(obj runtime.Object) {
+	t.update(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (t *LatencyTracker) OnDeleted(obj runtime.Object) {
+	switch obj := obj.(type) {
+	case *v1.Pod:
+		t.mu.Lock()
+		delete(t.pods, obj.UID)
+		t.mu.Unlock()
+	case *v1.PersistentVolumeClaim:
+		t.mu.Lock()
+		delete(t.pending, obj.UID)
+		t.mu.Unlock()
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (t *LatencyTracker) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// update logs the latencies of a Pod whose conditions turned true since its
+// previous event.
+func (t *LatencyTracker) update(obj runtime.Object) {
+	if claim, ok := obj.(*v1.PersistentVolumeClaim); ok {
+		t.updateClaim(claim)
+		return
+	}
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	scheduled, scheduledOK := conditionTrueSince(pod, v1.PodScheduled)
+	ready, readyOK := conditionTrueSince(pod, v1.PodReady)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	previous, seen := t.pods[pod.UID]
+	t.pods[pod.UID] = &podConditions{scheduled: scheduledOK, ready: readyOK}
+	if !seen {
+		return
+	}
+	if scheduledOK && !previous.scheduled {
+		t.logger.Info("Pod scheduled", "pod_name", pod.Name, "namespace", pod.Namespace, "node", pod.Spec.NodeName, "scheduling_latency", scheduled.Sub(pod.CreationTimestamp.Time))
+	}
+	if scheduledOK && readyOK && !previous.ready {
+		t.logger.Info("Pod ready", "pod_name", pod.Name, "namespace", pod.Namespace, "ready_latency", ready.Sub(scheduled), "total_latency", ready.Sub(pod.CreationTimestamp.Time))
+	}
+}
+
+// updateClaim logs the binding latency of a claim that was pending and is
+// now bound. Claims already bound when first seen are not reported, as the
+// time of their binding is unknown.
+func (t *LatencyTracker) updateClaim(claim *v1.PersistentVolumeClaim) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	switch claim.Status.Phase {
+	case v1.ClaimPending:
+		t.pending[claim.UID] = true
+	case v1.ClaimBound:
+		if t.pending[claim.UID] {
+			delete(t.pending, claim.UID)
+			t.logger.Info("PersistentVolumeClaim bound", "persistentvolumeclaim_name", claim.Name, "namespace", claim.Namespace, "volume", claim.Spec.VolumeName, "binding_latency", time.Since(claim.CreationTimestamp.Time).Round(time.Millisecond))
+		}
+	default:
+		delete(t.pending, claim.UID)
+	}
+}
+
+// conditionTrueSince returns when the condition of the given type of pod
+// turned true, if it is true.
+func conditionTrueSince(pod *v1.Pod, conditionType v1.PodConditionType) (time.Time, bool) {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == conditionType {
+			return condition.LastTransitionTime.Time, condition.Status == v1.ConditionTrue
+		}
+	}
+	return time.Time{}, false
+}
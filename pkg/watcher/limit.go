@@ -0,0 +1,60 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EventLimit is an EventHandler stopping the watch once a number of events,
+// not counting bookmarks, has been processed. It may be shared between
+// watchers and keeps counting across reconnects.
+type EventLimit struct {
+	logger *slog.Logger
+	max    int64
+	count  atomic.Int64
+	cancel context.CancelFunc
+}
+
+// NewEventLimit returns an EventLimit calling cancel once max events have
+// been processed.
+func NewEventLimit(logger *slog.Logger, max int, cancel context.CancelFunc) *EventLimit {
+	return &EventLimit{
+		logger: logger,
+		max:    int64(max),
+		cancel: cancel,
+	}
+}
+
+// Reached reports whether the limit has been reached.
+func (l *EventLimit) Reached() bool {
+	return l.count.Load() >= l.max
+}
+
+// OnAdded implements EventHandler.
+func (l *EventLimit) OnAdded(obj runtime.Object) {
+	l.observe()
+}
+
+// OnModified implements EventHandler.
+func (l *EventLimit) OnModified(obj runtime.Object) {
+	l.observe()
+}
+
+// OnDeleted implements EventHandler.
+func (l *EventLimit) OnDeleted(obj runtime.Object) {
+	l.observe()
+}
+
+// OnBookmark implements EventHandler.
+func (l *EventLimit) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// observe counts an event and stops the watch when it is the last one.
+func (l *EventLimit) observe() {
+	if l.count.Add(1) == l.max {
+		l.logger.Info("Maximum number of events reached, stopping", "max_events", l.max)
+		l.cancel()
+	}
+}
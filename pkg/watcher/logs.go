@@ -0,0 +1,185 @@
+package watcher
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+)
+
+// LogTailer is an EventHandler following the container logs of every Pod
+// once it is running and ready, until the Pod is deleted. Each log line is
+// prefixed with the Pod name. A container whose stream ended, e.g. because
+// it restarted, is followed again on the next event of its Pod, from when
+// the previous stream ended.
+type LogTailer struct {
+	// ctx bounds all log streams; it is the context of the whole run.
+	ctx       context.Context
+	clientset kubernetes.Interface
+	logger    *slog.Logger
+
+	outMu sync.Mutex
+	out   io.Writer
+
+	mu sync.Mutex
+	// tailing holds the stream of each followed container by
+	// namespace/pod/container
+	tailing map[string]*logStream
+	// ended holds when the last stream of a container ended, by the same
+	// key, so following it again skips the lines already written
+	ended map[string]time.Time
+}
+
+// logStream is a followed container log.
+type logStream struct {
+	cancel context.CancelFunc
+}
+
+// NewLogTailer returns a LogTailer writing log lines to out. The log streams
+// are stopped when ctx is cancelled.
+func NewLogTailer(ctx context.Context, clientset kubernetes.Interface, logger *slog.Logger, out io.Writer) *LogTailer {
+	return &LogTailer{
+		ctx:       ctx,
+		clientset: clientset,
+		logger:    logger,
+		out:       out,
+		tailing:   make(map[string]*logStream),
+		ended:     make(map[string]time.Time),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (t *LogTailer) OnAdded(obj runtime.Object) {
+	t.update(obj)
+}
+
+// OnModified implements EventHandler.
+func (t *LogTailer) OnModified(obj runtime.Object) {
+	t.update(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (t *LogTailer) OnDeleted(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	prefix := pod.Namespace + "/" + pod.Name + "/"
+	t.mu.Lock()
+	var streams []*logStream
+	for key, stream := range t.tailing {
+		if strings.HasPrefix(key, prefix) {
+			streams = append(streams, stream)
+			delete(t.tailing, key)
+		}
+	}
+	for key := range t.ended {
+		if strings.HasPrefix(key, prefix) {
+			delete(t.ended, key)
+		}
+	}
+	t.mu.Unlock()
+	if len(streams) > 0 {
+		t.logger.Info("Pod deleted, stopping log stream", "namespace", pod.Namespace, "pod_name", pod.Name)
+	}
+	for _, stream := range streams {
+		stream.cancel()
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (t *LogTailer) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// update starts following the logs of a Pod that became running and ready.
+func (t *LogTailer) update(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok || pod.Status.Phase != v1.PodRunning || !podReady(pod) {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, container := range pod.Spec.Containers {
+		key := pod.Namespace + "/" + pod.Name + "/" + container.Name
+		if _, ok := t.tailing[key]; ok {
+			continue
+		}
+		ctx, cancel := context.WithCancel(t.ctx)
+		stream := &logStream{cancel: cancel}
+		t.tailing[key] = stream
+		since, resumed := t.ended[key]
+
+		prefix := pod.Name
+		if len(pod.Spec.Containers) > 1 {
+			prefix += "/" + container.Name
+		}
+		t.logger.Info("Pod ready, following its logs", "namespace", pod.Namespace, "pod_name", pod.Name, "container", container.Name)
+		go func() {
+			var ended time.Time
+			defer func() { t.release(key, stream, ended) }()
+			opts := &v1.PodLogOptions{Container: container.Name, Follow: true}
+			if resumed {
+				opts.SinceTime = &metav1.Time{Time: since}
+			}
+			ended = t.follow(ctx, pod.Namespace, pod.Name, opts, prefix)
+		}()
+	}
+}
+
+// release forgets the stream of key once it ended, so the container can be
+// followed again from when it ended, unless it never started.
+func (t *LogTailer) release(key string, stream *logStream, ended time.Time) {
+	t.mu.Lock()
+	if t.tailing[key] == stream {
+		delete(t.tailing, key)
+		if !ended.IsZero() {
+			t.ended[key] = ended
+		}
+	}
+	t.mu.Unlock()
+	stream.cancel()
+}
+
+// follow copies the log stream selected by opts to the output until the
+// stream ends or ctx is cancelled. It returns when the stream ended, or the
+// zero time if it could not be opened.
+func (t *LogTailer) follow(ctx context.Context, namespace, name string, opts *v1.PodLogOptions, prefix string) time.Time {
+	container := opts.Container
+	stream, err := t.clientset.CoreV1().Pods(namespace).GetLogs(name, opts).Stream(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			t.logger.Warn("Failed to stream logs", "namespace", namespace, "pod_name", name, "container", container, "error", err)
+		}
+		return time.Time{}
+	}
+	defer stream.Close()
+
+	scanner := bufio.NewScanner(stream)
+	for scanner.Scan() {
+		t.outMu.Lock()
+		fmt.Fprintf(t.out, "[%s] %s\n", prefix, scanner.Text())
+		t.outMu.Unlock()
+	}
+	if err := scanner.Err(); err != nil && ctx.Err() == nil {
+		t.logger.Warn("Log stream failed", "namespace", namespace, "pod_name", name, "container", container, "error", err)
+	}
+	return time.Now()
+}
+
+// podReady reports whether the PodReady condition of pod is true.
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package watcher
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+var (
+	// eventsTotal counts the watch events received, by event type.
+	eventsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "watch_events_total",
+		Help: "Number of watch events received, by event type.",
+	}, []string{"event_type"})
+
+	// decodeErrorsTotal counts the watch events whose object failed to decode.
+	decodeErrorsTotal = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "watch_decode_errors_total",
+		Help: "Number of watch events whose object could not be decoded.",
+	})
+
+	// reconnectsTotal counts how often the watch has been re-established.
+	reconnectsTotal = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "watch_reconnects_total",
+		Help: "Number of times the watch has been re-established.",
+	})
+)
+
+// RegisterMetrics registers the watcher metrics with registerer.
+func RegisterMetrics(registerer prometheus.Registerer) error {
+	for _, collector := range []prometheus.Collector{eventsTotal, decodeErrorsTotal, reconnectsTotal} {
+		if err := registerer.Register(collector); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recordEvent increments the event counter for eventType.
+func recordEvent(eventType watch.EventType) {
+	eventsTotal.WithLabelValues(strings.ToLower(string(eventType))).Inc()
+}
@@ -0,0 +1,78 @@
+package watcher
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ndjsonEvent is the line written for each event by an NDJSONOutput.
+type ndjsonEvent struct {
+	Timestamp       string            `json:"timestamp"`
+	Type            string            `json:"type"`
+	Kind            string            `json:"kind,omitempty"`
+	Namespace       string            `json:"namespace,omitempty"`
+	Name            string            `json:"name,omitempty"`
+	UID             string            `json:"uid,omitempty"`
+	Phase           string            `json:"phase,omitempty"`
+	ResourceVersion string            `json:"resourceVersion"`
+	Annotations     map[string]string `json:"annotations,omitempty"`
+}
+
+// NDJSONOutput is an Output writing every event as a single line of JSON,
+// suitable for piping into jq or other consumers.
+type NDJSONOutput struct {
+	encoder *json.Encoder
+}
+
+// NewNDJSONOutput returns an NDJSONOutput writing to out.
+func NewNDJSONOutput(out io.Writer) *NDJSONOutput {
+	// Each line is encoded with a single write
+	return &NDJSONOutput{encoder: json.NewEncoder(NewSyncWriter(out))}
+}
+
+// Emit implements Output.
+func (o *NDJSONOutput) Emit(event Event) {
+	line := newNDJSONEvent(string(event.Type), event.Object)
+	if event.Type == watch.Bookmark {
+		if accessor, err := meta.Accessor(event.Object); err == nil {
+			line.Annotations = accessor.GetAnnotations()
+		}
+	}
+	// The encoder appends the newline
+	_ = o.encoder.Encode(line)
+}
+
+// newNDJSONEvent builds the line for an event of eventType on obj.
+func newNDJSONEvent(eventType string, obj runtime.Object) ndjsonEvent {
+	event := ndjsonEvent{
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Type:      eventType,
+		Kind:      kindOf(obj),
+	}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		event.Namespace = accessor.GetNamespace()
+		event.Name = accessor.GetName()
+		event.UID = string(accessor.GetUID())
+		event.ResourceVersion = accessor.GetResourceVersion()
+	}
+	if pod, ok := obj.(*v1.Pod); ok {
+		event.Phase = string(pod.Status.Phase)
+	}
+	return event
+}
+
+// kindOf returns the kind of a typed object known to the client-go scheme.
+func kindOf(obj runtime.Object) string {
+	gvks, _, err := scheme.Scheme.ObjectKinds(obj)
+	if err != nil || len(gvks) == 0 {
+		return ""
+	}
+	return gvks[0].Kind
+}
@@ -0,0 +1,56 @@
+package watcher
+
+import (
+	"sync/atomic"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// OnlyNewFilter is an EventHandler dropping the Added events of the initial
+// list, forwarding only the events received after the initial-events-end
+// bookmark. Handlers keeping a cache of the objects, like the LogHandler,
+// still record the initial objects so later changes can be diffed.
+type OnlyNewFilter struct {
+	next   EventHandler
+	synced atomic.Bool
+}
+
+// cacheWarmer is implemented by handlers recording the objects they see, so
+// the objects withheld by an OnlyNewFilter are still recorded.
+type cacheWarmer interface {
+	warm(obj runtime.Object)
+}
+
+// NewOnlyNewFilter returns an OnlyNewFilter forwarding to next.
+func NewOnlyNewFilter(next EventHandler) *OnlyNewFilter {
+	return &OnlyNewFilter{next: next}
+}
+
+// OnAdded implements EventHandler.
+func (f *OnlyNewFilter) OnAdded(obj runtime.Object) {
+	if f.synced.Load() {
+		f.next.OnAdded(obj)
+		return
+	}
+	if warmer, ok := f.next.(cacheWarmer); ok {
+		warmer.warm(obj)
+	}
+}
+
+// OnModified implements EventHandler.
+func (f *OnlyNewFilter) OnModified(obj runtime.Object) {
+	f.next.OnModified(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (f *OnlyNewFilter) OnDeleted(obj runtime.Object) {
+	f.next.OnDeleted(obj)
+}
+
+// OnBookmark implements EventHandler.
+func (f *OnlyNewFilter) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	if initialEventsEnd {
+		f.synced.Store(true)
+	}
+	f.next.OnBookmark(obj, initialEventsEnd)
+}
@@ -0,0 +1,74 @@
+package watcher
+
+import (
+	"io"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Event is a single event passed to an Output.
+type Event struct {
+	Type   watch.EventType
+	Object runtime.Object
+	// InitialEventsEnd is set for the bookmark marking the end of the
+	// initial list.
+	InitialEventsEnd bool
+}
+
+// Output formats and writes events. Implementations are safe for concurrent
+// use, so the watchers of several resources can share one without
+// interleaving their output.
+type Output interface {
+	Emit(event Event)
+}
+
+// OutputHandler is an EventHandler emitting every event to an Output.
+type OutputHandler struct {
+	out Output
+}
+
+// NewOutputHandler returns an OutputHandler emitting to out.
+func NewOutputHandler(out Output) *OutputHandler {
+	return &OutputHandler{out: out}
+}
+
+// OnAdded implements EventHandler.
+func (h *OutputHandler) OnAdded(obj runtime.Object) {
+	h.out.Emit(Event{Type: watch.Added, Object: obj})
+}
+
+// OnModified implements EventHandler.
+func (h *OutputHandler) OnModified(obj runtime.Object) {
+	h.out.Emit(Event{Type: watch.Modified, Object: obj})
+}
+
+// OnDeleted implements EventHandler.
+func (h *OutputHandler) OnDeleted(obj runtime.Object) {
+	h.out.Emit(Event{Type: watch.Deleted, Object: obj})
+}
+
+// OnBookmark implements EventHandler.
+func (h *OutputHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	h.out.Emit(Event{Type: watch.Bookmark, Object: obj, InitialEventsEnd: initialEventsEnd})
+}
+
+// SyncWriter is an io.Writer serializing the writes to an underlying writer,
+// so that concurrent writers never interleave within a single write.
+type SyncWriter struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewSyncWriter returns a SyncWriter writing to w.
+func NewSyncWriter(w io.Writer) *SyncWriter {
+	return &SyncWriter{w: w}
+}
+
+// Write implements io.Writer.
+func (s *SyncWriter) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.w.Write(p)
+}
@@ -0,0 +1,182 @@
+package watcher
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+)
+
+const (
+	// ownerLookupTimeout bounds the API call resolving a ReplicaSet's owner.
+	ownerLookupTimeout = 10 * time.Second
+	// ownerFailureTTL is how long a failed lookup is remembered before the
+	// ReplicaSet is looked up again.
+	ownerFailureTTL = 30 * time.Second
+)
+
+// replicaSetOwner is the cached Deployment of a ReplicaSet.
+type replicaSetOwner struct {
+	deployment string
+	// failedAt is set when the lookup failed, until it is retried
+	failedAt time.Time
+}
+
+// OwnerFilter is an EventHandler forwarding only the events of objects owned
+// by a given workload to the next handler. Pods owned by a Deployment are
+// matched by walking from their ReplicaSet to the Deployment, caching the
+// ReplicaSet to Deployment mapping to avoid repeated API calls. Deleted
+// events are matched without API calls, since the ReplicaSet may already be
+// gone.
+type OwnerFilter struct {
+	clientset kubernetes.Interface
+	kind      string
+	name      string
+	next      EventHandler
+	logger    *slog.Logger
+
+	mu          sync.Mutex
+	ctx         context.Context
+	deployments map[string]replicaSetOwner
+	// matched holds the objects forwarded so far, to match their deletion
+	matched map[types.UID]bool
+}
+
+// NewOwnerFilter returns an OwnerFilter matching objects owned by the
+// workload kind/name, e.g. Deployment/nginx. The kind is case-insensitive.
+func NewOwnerFilter(clientset kubernetes.Interface, logger *slog.Logger, kind, name string, next EventHandler) *OwnerFilter {
+	return &OwnerFilter{
+		clientset:   clientset,
+		kind:        kind,
+		name:        name,
+		next:        next,
+		logger:      logger,
+		ctx:         context.Background(),
+		deployments: make(map[string]replicaSetOwner),
+		matched:     make(map[types.UID]bool),
+	}
+}
+
+// bind makes the lookups use ctx, the context of the running watch, so
+// they are abandoned once it is cancelled.
+func (f *OwnerFilter) bind(ctx context.Context) {
+	f.mu.Lock()
+	f.ctx = ctx
+	f.mu.Unlock()
+}
+
+// OnAdded implements EventHandler.
+func (f *OwnerFilter) OnAdded(obj runtime.Object) {
+	if f.matches(obj) {
+		f.next.OnAdded(obj)
+	}
+}
+
+// OnModified implements EventHandler.
+func (f *OwnerFilter) OnModified(obj runtime.Object) {
+	if f.matches(obj) {
+		f.next.OnModified(obj)
+	}
+}
+
+// OnDeleted implements EventHandler.
+func (f *OwnerFilter) OnDeleted(obj runtime.Object) {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	f.mu.Lock()
+	matched := f.matched[accessor.GetUID()]
+	delete(f.matched, accessor.GetUID())
+	f.mu.Unlock()
+	if matched || f.owns(accessor, false) {
+		f.next.OnDeleted(obj)
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (f *OwnerFilter) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	f.next.OnBookmark(obj, initialEventsEnd)
+}
+
+// matches reports whether obj is owned by the workload, remembering the
+// objects that are so their deletion can be matched.
+func (f *OwnerFilter) matches(obj runtime.Object) bool {
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return false
+	}
+	if !f.owns(accessor, true) {
+		return false
+	}
+	if uid := accessor.GetUID(); uid != "" {
+		f.mu.Lock()
+		f.matched[uid] = true
+		f.mu.Unlock()
+	}
+	return true
+}
+
+// owns reports whether obj is owned by the workload. Without lookup only
+// the cached ReplicaSet owners are used.
+func (f *OwnerFilter) owns(obj metav1.Object, lookup bool) bool {
+	for _, ref := range obj.GetOwnerReferences() {
+		if strings.EqualFold(ref.Kind, f.kind) && ref.Name == f.name {
+			return true
+		}
+		if strings.EqualFold(f.kind, "Deployment") && ref.Kind == "ReplicaSet" && f.deploymentOf(obj.GetNamespace(), ref, lookup) == f.name {
+			return true
+		}
+	}
+	return false
+}
+
+// deploymentOf returns the name of the Deployment owning the ReplicaSet
+// referenced by ref, or an empty string if it has none or it is unknown.
+// Unless lookup is set only the cache is used.
+func (f *OwnerFilter) deploymentOf(namespace string, ref metav1.OwnerReference, lookup bool) string {
+	key := namespace + "/" + ref.Name
+	f.mu.Lock()
+	owner, ok := f.deployments[key]
+	ctx := f.ctx
+	f.mu.Unlock()
+	if ok && (owner.failedAt.IsZero() || time.Since(owner.failedAt) < ownerFailureTTL) {
+		return owner.deployment
+	}
+	if !lookup {
+		return ""
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ownerLookupTimeout)
+	defer cancel()
+	replicaSet, err := f.clientset.AppsV1().ReplicaSets(namespace).Get(ctx, ref.Name, metav1.GetOptions{})
+	if err != nil {
+		// Remember the failure for a while instead of stalling every event
+		if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			f.logger.Warn("Failed to look up ReplicaSet owner", "namespace", namespace, "replicaset", ref.Name, "retry_after", ownerFailureTTL, "error", err)
+		}
+		f.mu.Lock()
+		f.deployments[key] = replicaSetOwner{failedAt: time.Now()}
+		f.mu.Unlock()
+		return ""
+	}
+	owner = replicaSetOwner{}
+	for _, ref := range replicaSet.OwnerReferences {
+		if ref.Kind == "Deployment" {
+			owner.deployment = ref.Name
+		}
+	}
+
+	f.mu.Lock()
+	f.deployments[key] = owner
+	f.mu.Unlock()
+	return owner.deployment
+}
@@ -0,0 +1,72 @@
+package watcher
+
+import (
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// EventRate is an EventHandler estimating the current number of events per
+// second, not counting bookmarks, as an exponentially weighted moving
+// average. Older events lose half their weight every half-life. It may be
+// shared between watchers.
+type EventRate struct {
+	// decay is ln(2) divided by the half-life in seconds.
+	decay float64
+
+	mu    sync.Mutex
+	value float64
+	last  time.Time
+}
+
+// NewEventRate returns an EventRate averaging over halfLife.
+func NewEventRate(halfLife time.Duration) *EventRate {
+	return &EventRate{decay: math.Ln2 / halfLife.Seconds(), last: time.Now()}
+}
+
+// PerSecond returns the current rate in events per second. It returns 0 for
+// a nil EventRate.
+func (r *EventRate) PerSecond() float64 {
+	if r == nil {
+		return 0
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.value * math.Exp(-r.decay*time.Since(r.last).Seconds())
+}
+
+// OnAdded implements EventHandler.
+func (r *EventRate) OnAdded(obj runtime.Object) {
+	r.observe()
+}
+
+// OnModified implements EventHandler.
+func (r *EventRate) OnModified(obj runtime.Object) {
+	r.observe()
+}
+
+// OnDeleted implements EventHandler.
+func (r *EventRate) OnDeleted(obj runtime.Object) {
+	r.observe()
+}
+
+// OnBookmark implements EventHandler.
+func (r *EventRate) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// observe decays the average to now and adds an event, whose weight
+// integrates to one over time so a steady rate converges to itself.
+func (r *EventRate) observe() {
+	now := time.Now()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.value = r.value*math.Exp(-r.decay*now.Sub(r.last).Seconds()) + r.decay
+	r.last = now
+}
+
+// formatRate formats an event rate for logging with two decimals.
+func formatRate(rate float64) string {
+	return strconv.FormatFloat(rate, 'f', 2, 64)
+}
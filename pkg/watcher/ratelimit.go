@@ -0,0 +1,99 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// RateLimiter throttles how many events reach the handlers of the watchers
+// sharing it. Events over the limit are dropped but counted, and the number
+// of suppressed events is logged periodically.
+type RateLimiter struct {
+	limiter    *rate.Limiter
+	logger     *slog.Logger
+	suppressed atomic.Int64
+}
+
+// NewRateLimiter returns a RateLimiter allowing eventsPerSecond events.
+func NewRateLimiter(logger *slog.Logger, eventsPerSecond float64) *RateLimiter {
+	return &RateLimiter{
+		limiter: rate.NewLimiter(rate.Limit(eventsPerSecond), max(1, int(eventsPerSecond))),
+		logger:  logger,
+	}
+}
+
+// Run logs how many events were suppressed every interval until ctx is
+// cancelled.
+func (r *RateLimiter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			r.report()
+			return
+		case <-ticker.C:
+			r.report()
+		}
+	}
+}
+
+// report logs and resets the number of suppressed events, if any.
+func (r *RateLimiter) report() {
+	if n := r.suppressed.Swap(0); n > 0 {
+		r.logger.Warn("Rate limit exceeded, suppressed events", "suppressed", n)
+	}
+}
+
+// Wrap returns an EventHandler forwarding to next within the rate limit.
+// Bookmarks are always forwarded since handlers rely on them.
+func (r *RateLimiter) Wrap(next EventHandler) EventHandler {
+	return &rateLimitedHandler{limiter: r, next: next}
+}
+
+// allow reports whether another event may be forwarded, counting it as
+// suppressed otherwise.
+func (r *RateLimiter) allow() bool {
+	if r.limiter.Allow() {
+		return true
+	}
+	r.suppressed.Add(1)
+	return false
+}
+
+// rateLimitedHandler is the EventHandler returned by RateLimiter.Wrap.
+type rateLimitedHandler struct {
+	limiter *RateLimiter
+	next    EventHandler
+}
+
+// OnAdded implements EventHandler.
+func (h *rateLimitedHandler) OnAdded(obj runtime.Object) {
+	if h.limiter.allow() {
+		h.next.OnAdded(obj)
+	}
+}
+
+// OnModified implements EventHandler.
+func (h *rateLimitedHandler) OnModified(obj runtime.Object) {
+	if h.limiter.allow() {
+		h.next.OnModified(obj)
+	}
+}
+
+// OnDeleted implements EventHandler.
+func (h *rateLimitedHandler) OnDeleted(obj runtime.Object) {
+	if h.limiter.allow() {
+		h.next.OnDeleted(obj)
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (h *rateLimitedHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	h.next.OnBookmark(obj, initialEventsEnd)
+}
@@ -0,0 +1,107 @@
+package watcher
+
+import (
+	"sync"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// Values of Options.ReadyFilter.
+const (
+	// ReadyFilterReady only passes Pods whose PodReady condition is true.
+	ReadyFilterReady = "ready"
+	// ReadyFilterNotReady only passes Pods that are not ready.
+	ReadyFilterNotReady = "not-ready"
+)
+
+// ReadyFilter is an EventHandler forwarding only the events of Pods in the
+// requested readiness state to the next handler. A Pod crossing the filter
+// boundary is forwarded as Added when it enters the filtered view and as
+// Deleted when it leaves it. Other objects are forwarded unchanged.
+type ReadyFilter struct {
+	ready bool
+	next  EventHandler
+
+	mu sync.Mutex
+	// visible holds the Pods currently passed to next.
+	visible map[types.UID]bool
+}
+
+// NewReadyFilter returns a ReadyFilter passing ready Pods when ready is true
+// and Pods that are not ready otherwise.
+func NewReadyFilter(ready bool, next EventHandler) *ReadyFilter {
+	return &ReadyFilter{
+		ready:   ready,
+		next:    next,
+		visible: make(map[types.UID]bool),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (f *ReadyFilter) OnAdded(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		f.next.OnAdded(obj)
+		return
+	}
+	if f.update(pod) {
+		f.next.OnAdded(obj)
+	}
+}
+
+// OnModified implements EventHandler.
+func (f *ReadyFilter) OnModified(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		f.next.OnModified(obj)
+		return
+	}
+	f.mu.Lock()
+	was := f.visible[pod.UID]
+	f.mu.Unlock()
+	is := f.update(pod)
+	switch {
+	case was && is:
+		f.next.OnModified(obj)
+	case is:
+		f.next.OnAdded(obj)
+	case was:
+		f.next.OnDeleted(obj)
+	}
+}
+
+// OnDeleted implements EventHandler.
+func (f *ReadyFilter) OnDeleted(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		f.next.OnDeleted(obj)
+		return
+	}
+	f.mu.Lock()
+	was := f.visible[pod.UID]
+	delete(f.visible, pod.UID)
+	f.mu.Unlock()
+	if was {
+		f.next.OnDeleted(obj)
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (f *ReadyFilter) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	f.next.OnBookmark(obj, initialEventsEnd)
+}
+
+// update records whether pod is in the filtered view and returns it.
+func (f *ReadyFilter) update(pod *v1.Pod) bool {
+	matches := podReady(pod) == f.ready
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if matches {
+		f.visible[pod.UID] = true
+	} else {
+		delete(f.visible, pod.UID)
+	}
+	return matches
+}
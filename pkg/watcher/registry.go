@@ -0,0 +1,72 @@
+package watcher
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// Registry is an EventHandler fanning every event out to the registered
+// handlers in the order they were registered. A handler that panics is
+// logged and skipped for that event without affecting the others.
+type Registry struct {
+	logger *slog.Logger
+
+	mu       sync.RWMutex
+	handlers []EventHandler
+}
+
+// NewRegistry returns an empty Registry logging failing handlers to logger.
+func NewRegistry(logger *slog.Logger) *Registry {
+	return &Registry{logger: logger}
+}
+
+// Register adds handler to the handlers receiving the events.
+func (r *Registry) Register(handler EventHandler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+// OnAdded implements EventHandler.
+func (r *Registry) OnAdded(obj runtime.Object) {
+	r.each("ADDED", func(h EventHandler) { h.OnAdded(obj) })
+}
+
+// OnModified implements EventHandler.
+func (r *Registry) OnModified(obj runtime.Object) {
+	r.each("MODIFIED", func(h EventHandler) { h.OnModified(obj) })
+}
+
+// OnDeleted implements EventHandler.
+func (r *Registry) OnDeleted(obj runtime.Object) {
+	r.each("DELETED", func(h EventHandler) { h.OnDeleted(obj) })
+}
+
+// OnBookmark implements EventHandler.
+func (r *Registry) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	r.each("BOOKMARK", func(h EventHandler) { h.OnBookmark(obj, initialEventsEnd) })
+}
+
+// each calls fn for every registered handler.
+func (r *Registry) each(eventType string, fn func(EventHandler)) {
+	r.mu.RLock()
+	handlers := r.handlers
+	r.mu.RUnlock()
+	for _, h := range handlers {
+		r.call(eventType, h, fn)
+	}
+}
+
+// call calls fn for h, recovering from a panic so the remaining handlers
+// still receive the event.
+func (r *Registry) call(eventType string, h EventHandler, fn func(EventHandler)) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.Error("Event handler failed", "event_type", eventType, "handler", fmt.Sprintf("%T", h), "error", p)
+		}
+	}()
+	fn(h)
+}
@@ -0,0 +1,182 @@
+package watcher
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// maxReplayLine bounds the length of a line of a replayed NDJSON file.
+const maxReplayLine = 1024 * 1024
+
+// Replayer feeds events recorded as NDJSON by an NDJSONOutput to the same
+// handlers as a Watcher, keeping their relative timing. It does not need a
+// cluster. Only the events of the resource in its options are replayed.
+type Replayer struct {
+	path      string
+	namespace string
+	options   Options
+	kind      string
+	processed atomic.Int64
+	synced    atomic.Bool
+	debug     debugState
+}
+
+// NewReplayer returns a Replayer for the events recorded in path within
+// namespace. Use metav1.NamespaceAll to replay every namespace. Options
+// needing a cluster, such as OwnerName, are not supported.
+func NewReplayer(path, namespace string, options Options) (*Replayer, error) {
+	if options.OwnerName != "" {
+		return nil, errors.New("replaying does not support owner filters")
+	}
+	if ClusterScoped(options.Resource) {
+		namespace = ""
+	}
+	kind, err := options.complete(nil, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &Replayer{
+		path:      path,
+		namespace: namespace,
+		options:   options,
+		kind:      kind,
+	}, nil
+}
+
+// Processed returns the number of events replayed so far.
+func (r *Replayer) Processed() int {
+	return int(r.processed.Load())
+}
+
+// Synced reports whether the end of the recorded initial list has been
+// replayed.
+func (r *Replayer) Synced() bool {
+	return r.synced.Load()
+}
+
+// State returns a snapshot of the internal state of the replay.
+func (r *Replayer) State() State {
+	state := State{
+		Resource:  r.options.Resource,
+		Namespace: r.namespace,
+		Processed: r.Processed(),
+		Synced:    r.Synced(),
+	}
+	r.debug.fill(&state)
+	return state
+}
+
+// Run replays the recorded events until the end of the file or until ctx is
+// cancelled.
+func (r *Replayer) Run(ctx context.Context) error {
+	logger := r.options.Logger
+	logger.Info("Replaying recorded events", "path", r.path, "resource", r.options.Resource, "namespace", r.namespace)
+
+	f, err := os.Open(r.path)
+	if err != nil {
+		return fmt.Errorf("opening replay file: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxReplayLine)
+	start := time.Now()
+	var first time.Time
+	for line := 1; scanner.Scan(); line++ {
+		var event ndjsonEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			logger.Warn("Skipping invalid replay line", "line", line, "error", err)
+			continue
+		}
+		if !r.matches(event) {
+			continue
+		}
+
+		// Keep the recorded delay between events
+		if timestamp, err := time.Parse(time.RFC3339Nano, event.Timestamp); err == nil {
+			if first.IsZero() {
+				first = timestamp
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(time.Until(start.Add(timestamp.Sub(first)))):
+			}
+		}
+
+		if err := r.dispatch(event); err != nil {
+			logger.Warn("Skipping replay line", "line", line, "error", err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading replay file: %w", err)
+	}
+
+	r.synced.Store(true)
+	logger.Info("Replay finished", "events", r.Processed())
+	return nil
+}
+
+// matches reports whether a recorded event belongs to the replayed resource
+// and namespace. Bookmarks without a kind are replayed for every resource.
+func (r *Replayer) matches(event ndjsonEvent) bool {
+	if event.Kind != "" && event.Kind != r.kind {
+		return false
+	}
+	return r.namespace == "" || event.Namespace == "" || event.Namespace == r.namespace
+}
+
+// dispatch rebuilds the object of a recorded event and hands it to the
+// handler.
+func (r *Replayer) dispatch(event ndjsonEvent) error {
+	obj, err := newObject(r.options.Resource)
+	if err != nil {
+		return err
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return err
+	}
+	accessor.SetNamespace(event.Namespace)
+	accessor.SetName(event.Name)
+	accessor.SetUID(types.UID(event.UID))
+	accessor.SetResourceVersion(event.ResourceVersion)
+	accessor.SetAnnotations(event.Annotations)
+	if pod, ok := obj.(*v1.Pod); ok {
+		pod.Status.Phase = v1.PodPhase(event.Phase)
+	}
+
+	r.processed.Add(1)
+	eventType := watch.EventType(event.Type)
+	r.options.Stats.observe(eventType)
+	r.debug.observe(eventType, event.ResourceVersion)
+	handler := r.options.Handler
+	switch eventType {
+	case watch.Added:
+		handler.OnAdded(obj)
+	case watch.Modified:
+		handler.OnModified(obj)
+	case watch.Deleted:
+		handler.OnDeleted(obj)
+	case watch.Bookmark:
+		initialEventsEnd := event.Annotations[r.options.InitialEventsAnnotation] == "true"
+		if initialEventsEnd {
+			r.synced.Store(true)
+		}
+		handler.OnBookmark(obj, initialEventsEnd)
+	default:
+		return fmt.Errorf("unknown event type %q", event.Type)
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package watcher
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+)
+
+// resourceInfo describes a supported resource: how it is named, where it
+// lives and how to reach it through the typed clients.
+type resourceInfo struct {
+	// kind is logged for the events of the resource
+	kind string
+	// group is the API group, empty for the core group
+	group string
+	// clusterScoped is set for resources that do not live in a namespace
+	clusterScoped bool
+
+	watch     func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error)
+	list      func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error)
+	informer  func(factory informers.SharedInformerFactory) cache.SharedIndexInformer
+	newObject func() runtime.Object
+	// events is the event loop of the typed objects of the resource
+	events eventLoop
+}
+
+// resources holds the supported resources by name. Adding a resource only
+// takes an entry here.
+var resources = map[string]resourceInfo{
+	"pods": {
+		kind: "Pod",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Pods(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Pods(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Pods().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Pod{} },
+		events:    typedEvents[*v1.Pod]{},
+	},
+	"services": {
+		kind: "Service",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Services(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Services(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Services().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Service{} },
+		events:    typedEvents[*v1.Service]{},
+	},
+	"configmaps": {
+		kind: "ConfigMap",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().ConfigMaps(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().ConfigMaps(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().ConfigMaps().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.ConfigMap{} },
+		events:    typedEvents[*v1.ConfigMap]{},
+	},
+	"secrets": {
+		kind: "Secret",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Secrets(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Secrets(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Secrets().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Secret{} },
+		events:    typedEvents[*v1.Secret]{},
+	},
+	"endpoints": {
+		kind: "Endpoints",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Endpoints(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Endpoints(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Endpoints().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Endpoints{} },
+		events:    typedEvents[*v1.Endpoints]{},
+	},
+	"events": {
+		kind: "Event",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Events(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Events(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Events().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Event{} },
+		events:    typedEvents[*v1.Event]{},
+	},
+	"persistentvolumeclaims": {
+		kind: "PersistentVolumeClaim",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().PersistentVolumeClaims(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().PersistentVolumeClaims(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().PersistentVolumeClaims().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.PersistentVolumeClaim{} },
+		events:    typedEvents[*v1.PersistentVolumeClaim]{},
+	},
+	"deployments": {
+		kind:  "Deployment",
+		group: "apps",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.AppsV1().Deployments(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.AppsV1().Deployments(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Apps().V1().Deployments().Informer()
+		},
+		newObject: func() runtime.Object { return &appsv1.Deployment{} },
+		events:    typedEvents[*appsv1.Deployment]{},
+	},
+	"jobs": {
+		kind:  "Job",
+		group: "batch",
+		watch: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.BatchV1().Jobs(namespace).Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.BatchV1().Jobs(namespace).List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Batch().V1().Jobs().Informer()
+		},
+		newObject: func() runtime.Object { return &batchv1.Job{} },
+		events:    typedEvents[*batchv1.Job]{},
+	},
+	"nodes": {
+		kind:          "Node",
+		clusterScoped: true,
+		watch: func(ctx context.Context, clientset kubernetes.Interface, _ string, opts metav1.ListOptions) (watch.Interface, error) {
+			return clientset.CoreV1().Nodes().Watch(ctx, opts)
+		},
+		list: func(ctx context.Context, clientset kubernetes.Interface, _ string, opts metav1.ListOptions) (runtime.Object, error) {
+			return clientset.CoreV1().Nodes().List(ctx, opts)
+		},
+		informer: func(factory informers.SharedInformerFactory) cache.SharedIndexInformer {
+			return factory.Core().V1().Nodes().Informer()
+		},
+		newObject: func() runtime.Object { return &v1.Node{} },
+		events:    typedEvents[*v1.Node]{},
+	},
+}
+
+// lookupResource returns the description of resource.
+func lookupResource(resource string) (resourceInfo, error) {
+	info, ok := resources[resource]
+	if !ok {
+		return resourceInfo{}, fmt.Errorf("unsupported resource %q", resource)
+	}
+	return info, nil
+}
+
+// ClusterScoped reports whether resource is watched across the cluster
+// regardless of the namespace.
+func ClusterScoped(resource string) bool {
+	return resources[resource].clusterScoped
+}
+
+// watchResource selects the typed client for resource and starts a watch on it.
+func watchResource(ctx context.Context, clientset kubernetes.Interface, resource, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	info, err := lookupResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return info.watch(ctx, clientset, namespace, opts)
+}
+
+// listResource selects the typed client for resource and lists it.
+func listResource(ctx context.Context, clientset kubernetes.Interface, resource, namespace string, opts metav1.ListOptions) (runtime.Object, error) {
+	info, err := lookupResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return info.list(ctx, clientset, namespace, opts)
+}
+
+// informerFor returns the shared informer of factory for resource.
+func informerFor(factory informers.SharedInformerFactory, resource string) (cache.SharedIndexInformer, error) {
+	info, err := lookupResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return info.informer(factory), nil
+}
+
+// newObject returns an empty typed object of resource.
+func newObject(resource string) (runtime.Object, error) {
+	info, err := lookupResource(resource)
+	if err != nil {
+		return nil, err
+	}
+	return info.newObject(), nil
+}
@@ -0,0 +1,127 @@
+package watcher
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// maxKnownObjects bounds the objects tracked for CacheResync. Objects seen
+// once it is reached are not pruned.
+const maxKnownObjects = 100000
+
+// track records obj as present for CacheResync. It is only called from the
+// event loop.
+func (w *Watcher) track(obj runtime.Object) {
+	if w.options.CacheResync <= 0 {
+		return
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return
+	}
+	uid := accessor.GetUID()
+	delete(w.pruned, uid)
+	if _, ok := w.known[uid]; !ok && len(w.known) >= maxKnownObjects {
+		if !w.knownFull {
+			w.knownFull = true
+			w.options.Logger.Warn("Too many objects for the cache resync, not tracking new ones", "max_objects", maxKnownObjects)
+		}
+		return
+	}
+	w.known[uid] = types.NamespacedName{Namespace: accessor.GetNamespace(), Name: accessor.GetName()}
+}
+
+// untrack forgets obj once it has been deleted. It reports false when the
+// object was already reported deleted by pruneStale, in which case its
+// Delete event must not be dispatched again.
+func (w *Watcher) untrack(obj runtime.Object) bool {
+	if w.options.CacheResync <= 0 {
+		return true
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return true
+	}
+	uid := accessor.GetUID()
+	delete(w.known, uid)
+	if w.pruned[uid] {
+		delete(w.pruned, uid)
+		return false
+	}
+	return true
+}
+
+// pruneStale lists the resource and dispatches a Deleted event for every
+// tracked object missing from the list, so that handlers drop the entries
+// whose Delete event was missed, e.g. across reconnects. It runs in the
+// event loop, which pauses while listing.
+func (w *Watcher) pruneStale(ctx context.Context) {
+	logger := w.options.Logger
+	present := make(map[types.UID]bool, len(w.known))
+	opts := metav1.ListOptions{
+		LabelSelector: w.options.LabelSelector,
+		FieldSelector: w.options.FieldSelector,
+		Limit:         int64(w.options.PageSize),
+	}
+	for {
+		list, err := w.list(ctx, opts)
+		if err != nil {
+			logger.Warn("Failed to list for the cache resync", "error", err)
+			return
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			logger.Warn("Failed to list for the cache resync", "error", err)
+			return
+		}
+		for _, item := range items {
+			if accessor, err := meta.Accessor(item); err == nil {
+				present[accessor.GetUID()] = true
+			}
+		}
+		listAccessor, err := meta.ListAccessor(list)
+		if err != nil || listAccessor.GetContinue() == "" {
+			break
+		}
+		opts.Continue = listAccessor.GetContinue()
+	}
+
+	// The Delete events of the objects pruned last time that still have not
+	// arrived are not expected anymore
+	clear(w.pruned)
+	for uid, name := range w.known {
+		if present[uid] {
+			continue
+		}
+		delete(w.known, uid)
+		w.pruned[uid] = true
+		w.options.Handler.OnDeleted(w.tombstone(uid, name))
+	}
+	if len(w.known) < maxKnownObjects {
+		w.knownFull = false
+	}
+	logger.Info("Cache resync complete", "objects", len(w.known), "pruned", len(w.pruned))
+}
+
+// tombstone returns an object of the watched resource carrying only the
+// metadata of a pruned object, enough for the handlers to drop it.
+func (w *Watcher) tombstone(uid types.UID, name types.NamespacedName) runtime.Object {
+	obj, err := newObject(w.options.Resource)
+	if w.dynamic != nil || err != nil {
+		u := &unstructured.Unstructured{}
+		if w.dynamic != nil {
+			u.SetAPIVersion(w.gvr.GroupVersion().String())
+		}
+		obj = u
+	}
+	accessor, _ := meta.Accessor(obj)
+	accessor.SetUID(uid)
+	accessor.SetNamespace(name.Namespace)
+	accessor.SetName(name.Name)
+	return obj
+}
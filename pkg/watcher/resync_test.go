@@ -0,0 +1,69 @@
+package watcher
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCacheResyncPrunesMissedDeleteOnce(t *testing.T) {
+	// Only web is left when the resync lists the Pods
+	clientset := fake.NewSimpleClientset(testPod("web", "3", v1.PodRunning))
+	handler := newRecordingHandler()
+	w, err := New(clientset, "default", Options{Handler: handler, Logger: discardLogger(), CacheResync: time.Hour})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resync := make(chan time.Time)
+	w.known = make(map[types.UID]types.NamespacedName)
+	w.pruned = make(map[types.UID]bool)
+	w.resync = resync
+
+	fakeWatch := watch.NewFake()
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := processEvents[*v1.Pod](w, context.Background(), fakeWatch.ResultChan(), "", false)
+		done <- err
+	}()
+
+	fakeWatch.Add(testPod("web", "1", v1.PodRunning))
+	fakeWatch.Add(testPod("db", "2", v1.PodRunning))
+	for _, name := range []string{"web", "db"} {
+		if event := handler.wait(t); event.eventType != watch.Added || event.name != name {
+			t.Fatalf("got callback %+v, want Added of %s", event, name)
+		}
+	}
+
+	// The Delete event of db was missed, the resync reports it
+	resync <- time.Now()
+	if event := handler.wait(t); event.eventType != watch.Deleted || event.name != "db" {
+		t.Fatalf("got callback %+v, want Deleted of db", event)
+	}
+
+	// The late Delete event of db is not reported again, nor by the next
+	// resync
+	fakeWatch.Delete(testPod("db", "4", v1.PodRunning))
+	resync <- time.Now()
+	fakeWatch.Modify(testPod("web", "5", v1.PodRunning))
+	if event := handler.wait(t); event.eventType != watch.Modified || event.name != "web" {
+		t.Fatalf("got callback %+v, want Modified of web", event)
+	}
+	if len(w.known) != 1 || len(w.pruned) != 0 {
+		t.Errorf("tracking %d objects and %d pruned ones, want 1 and 0", len(w.known), len(w.pruned))
+	}
+
+	fakeWatch.Stop()
+	if err := <-done; err != nil {
+		t.Fatalf("processEvents returned %v", err)
+	}
+	select {
+	case event := <-handler.events:
+		t.Errorf("unexpected callback %+v", event)
+	default:
+	}
+}
@@ -0,0 +1,27 @@
+package watcher
+
+import "strconv"
+
+// resourceVersionTracker remembers the highest resource version processed so
+// regressions can be detected. Resource versions are opaque strings, so the
+// tracker only compares versions that parse as integers, which is what etcd
+// backed API servers return.
+type resourceVersionTracker struct {
+	last uint64
+}
+
+// observe records resourceVersion and reports the previously highest version
+// and whether resourceVersion is lower than it, or equal to it when
+// allowEqual is false. Unparseable versions are ignored.
+func (t *resourceVersionTracker) observe(resourceVersion string, allowEqual bool) (uint64, bool) {
+	current, err := strconv.ParseUint(resourceVersion, 10, 64)
+	if err != nil {
+		return t.last, false
+	}
+	previous := t.last
+	regressed := previous != 0 && (current < previous || (current == previous && !allowEqual))
+	if current > t.last {
+		t.last = current
+	}
+	return previous, regressed
+}
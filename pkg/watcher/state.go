@@ -0,0 +1,149 @@
+package watcher
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// stateSchemaVersion is the version of the state file format written by this
+// build. Files of any other version are rejected.
+const stateSchemaVersion = 1
+
+// watchState is the content of a state file.
+type watchState struct {
+	SchemaVersion   int       `json:"schemaVersion"`
+	ResourceVersion string    `json:"resourceVersion"`
+	SavedAt         time.Time `json:"savedAt"`
+}
+
+// validate checks a decoded state.
+func (s watchState) validate() error {
+	if s.SchemaVersion != stateSchemaVersion {
+		return fmt.Errorf("unsupported schema version %d, expected %d", s.SchemaVersion, stateSchemaVersion)
+	}
+	if s.ResourceVersion == "" {
+		return errors.New("missing resource version")
+	}
+	return nil
+}
+
+// loadResourceVersion returns the resource version saved in stateFile. A
+// missing file or an empty path yields an empty version. A malformed file or
+// one of an unknown schema version is rejected, except for the bare
+// resource version written by earlier builds.
+func loadResourceVersion(stateFile string) (string, error) {
+	if stateFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(stateFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	var state watchState
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&state); err != nil {
+		if resourceVersion, ok := legacyResourceVersion(data); ok {
+			return resourceVersion, nil
+		}
+		return "", fmt.Errorf("invalid state file %s, delete it to start with a full list: %w", stateFile, err)
+	}
+	if err := state.validate(); err != nil {
+		return "", fmt.Errorf("invalid state file %s, delete it to start with a full list: %w", stateFile, err)
+	}
+	return state.ResourceVersion, nil
+}
+
+// legacyResourceVersion returns the resource version of a state file written
+// before the JSON format, holding it alone on a single line.
+func legacyResourceVersion(data []byte) (string, bool) {
+	fields := strings.Fields(string(data))
+	if len(fields) != 1 || strings.HasPrefix(fields[0], "{") {
+		return "", false
+	}
+	return fields[0], true
+}
+
+// Without bookmarks, the resource version of the object events is saved
+// every checkpointInterval or checkpointEvents events, whichever comes first,
+// rather than syncing the state file to disk on every event.
+const (
+	checkpointInterval = 10 * time.Second
+	checkpointEvents   = 100
+)
+
+// noteCheckpoint records resourceVersion to be saved by the next
+// checkpoint, saving it right away once checkpointEvents are pending.
+func (w *Watcher) noteCheckpoint(resourceVersion string) {
+	w.unsaved = resourceVersion
+	w.unsavedEvents++
+	if w.unsavedEvents >= checkpointEvents {
+		w.saveCheckpoint()
+	}
+}
+
+// saveCheckpoint saves the resource version recorded by noteCheckpoint, if
+// any.
+func (w *Watcher) saveCheckpoint() {
+	if w.unsaved == "" {
+		return
+	}
+	if err := saveResourceVersion(w.options.StateFile, w.unsaved); err != nil {
+		w.options.Logger.Warn("Failed to write state file", "path", w.options.StateFile, "error", err)
+	}
+	w.unsaved, w.unsavedEvents = "", 0
+}
+
+// saveResourceVersion writes resourceVersion to stateFile. The file is
+// replaced atomically so a crash never leaves it truncated. An empty version
+// removes the file so the next run starts with a full list.
+func saveResourceVersion(stateFile, resourceVersion string) error {
+	if stateFile == "" {
+		return nil
+	}
+	if resourceVersion == "" {
+		if err := os.Remove(stateFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	data, err := json.Marshal(watchState{
+		SchemaVersion:   stateSchemaVersion,
+		ResourceVersion: resourceVersion,
+		SavedAt:         time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Write next to the file so the rename stays on the same file system
+	tmp, err := os.CreateTemp(filepath.Dir(stateFile), filepath.Base(stateFile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), stateFile)
+}
@@ -0,0 +1,78 @@
+package watcher
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadResourceVersion(t *testing.T) {
+	tests := []struct {
+		name    string
+		content *string
+		want    string
+		wantErr bool
+	}{
+		{name: "missing file"},
+		{name: "json", content: ptr(`{"schemaVersion":1,"resourceVersion":"42","savedAt":"2026-01-02T03:04:05Z"}` + "\n"), want: "42"},
+		{name: "legacy plain text", content: ptr("12345\n"), want: "12345"},
+		{name: "unknown schema version", content: ptr(`{"schemaVersion":2,"resourceVersion":"42"}`), wantErr: true},
+		{name: "unknown field", content: ptr(`{"schemaVersion":1,"resourceVersion":"42","extra":true}`), wantErr: true},
+		{name: "corrupt json", content: ptr(`{"schemaVersion":1,"resourceVer`), wantErr: true},
+		{name: "corrupt plain text", content: ptr("123 456\n"), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "state.json")
+			if tt.content != nil {
+				if err := os.WriteFile(path, []byte(*tt.content), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := loadResourceVersion(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadResourceVersion() error = %v, want error %t", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("loadResourceVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveResourceVersionRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	for _, resourceVersion := range []string{"1", "2"} {
+		if err := saveResourceVersion(path, resourceVersion); err != nil {
+			t.Fatal(err)
+		}
+		got, err := loadResourceVersion(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != resourceVersion {
+			t.Errorf("loadResourceVersion() = %q after saving %q", got, resourceVersion)
+		}
+	}
+
+	// No temporary file is left next to the state file
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("state directory holds %d entries, want 1", len(entries))
+	}
+
+	// An empty version starts over with a full list
+	if err := saveResourceVersion(path, ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("state file still present after saving an empty version: %v", err)
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
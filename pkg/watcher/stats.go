@@ -0,0 +1,63 @@
+package watcher
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// Stats counts the events received by type. It may be shared between
+// watchers and is safe for concurrent use. A nil Stats counts nothing.
+type Stats struct {
+	start     time.Time
+	added     atomic.Int64
+	modified  atomic.Int64
+	deleted   atomic.Int64
+	bookmarks atomic.Int64
+	errors    atomic.Int64
+}
+
+// NewStats returns a Stats measuring the runtime from now.
+func NewStats() *Stats {
+	return &Stats{start: time.Now()}
+}
+
+// observe counts an event of the given type.
+func (s *Stats) observe(eventType watch.EventType) {
+	if s == nil {
+		return
+	}
+	switch eventType {
+	case watch.Added:
+		s.added.Add(1)
+	case watch.Modified:
+		s.modified.Add(1)
+	case watch.Deleted:
+		s.deleted.Add(1)
+	case watch.Bookmark:
+		s.bookmarks.Add(1)
+	case watch.Error:
+		s.errors.Add(1)
+	}
+}
+
+// WriteTable writes the counters and the runtime as a table to out.
+func (s *Stats) WriteTable(out io.Writer) error {
+	added, modified, deleted := s.added.Load(), s.modified.Load(), s.deleted.Load()
+	bookmarks, errors := s.bookmarks.Load(), s.errors.Load()
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(w, "EVENT\tCOUNT\t\n")
+	fmt.Fprintf(w, "added\t%d\t\n", added)
+	fmt.Fprintf(w, "modified\t%d\t\n", modified)
+	fmt.Fprintf(w, "deleted\t%d\t\n", deleted)
+	fmt.Fprintf(w, "bookmark\t%d\t\n", bookmarks)
+	fmt.Fprintf(w, "error\t%d\t\n", errors)
+	fmt.Fprintf(w, "total\t%d\t\n", added+modified+deleted+bookmarks+errors)
+	fmt.Fprintf(w, "runtime\t%s\t\n", time.Since(s.start).Round(time.Millisecond))
+	return w.Flush()
+}
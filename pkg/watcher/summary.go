@@ -0,0 +1,104 @@
+package watcher
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// summaryPhases lists the Pod phases reported by a SummaryHandler, in order.
+var summaryPhases = []v1.PodPhase{v1.PodPending, v1.PodRunning, v1.PodSucceeded, v1.PodFailed, v1.PodUnknown}
+
+// SummaryHandler is an EventHandler that tracks the phase of every Pod
+// instead of logging each event, and periodically logs how many Pods are in
+// each phase.
+type SummaryHandler struct {
+	logger *slog.Logger
+	rate   *EventRate
+
+	mu     sync.Mutex
+	phases map[string]v1.PodPhase
+}
+
+// NewSummaryHandler returns a SummaryHandler logging to logger, including
+// the current event rate if rate is not nil.
+func NewSummaryHandler(logger *slog.Logger, rate *EventRate) *SummaryHandler {
+	return &SummaryHandler{
+		logger: logger,
+		rate:   rate,
+		phases: make(map[string]v1.PodPhase),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (h *SummaryHandler) OnAdded(obj runtime.Object) {
+	h.update(obj)
+}
+
+// OnModified implements EventHandler.
+func (h *SummaryHandler) OnModified(obj runtime.Object) {
+	h.update(obj)
+}
+
+// OnDeleted implements EventHandler.
+func (h *SummaryHandler) OnDeleted(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.phases, pod.Namespace+"/"+pod.Name)
+}
+
+// OnBookmark implements EventHandler.
+func (h *SummaryHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// Run logs a phase summary every interval until ctx is cancelled.
+func (h *SummaryHandler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.report()
+		}
+	}
+}
+
+// update records the current phase of a Pod.
+func (h *SummaryHandler) update(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.phases[pod.Namespace+"/"+pod.Name] = pod.Status.Phase
+}
+
+// report logs the number of tracked Pods in each phase.
+func (h *SummaryHandler) report() {
+	h.mu.Lock()
+	counts := make(map[v1.PodPhase]int, len(summaryPhases))
+	for _, phase := range h.phases {
+		counts[phase]++
+	}
+	total := len(h.phases)
+	h.mu.Unlock()
+
+	attrs := []any{"total", total}
+	for _, phase := range summaryPhases {
+		attrs = append(attrs, string(phase), counts[phase])
+	}
+	if h.rate != nil {
+		attrs = append(attrs, "events_per_second", formatRate(h.rate.PerSecond()))
+	}
+	h.logger.Info("Pod phase summary", attrs...)
+}
@@ -0,0 +1,112 @@
+package watcher
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// clearScreen moves the cursor home and clears the terminal.
+const clearScreen = "\033[H\033[2J"
+
+// TableOutput is an Output keeping the current Pods in memory and
+// re-rendering them as a table on every change, similar to
+// kubectl get pods -w.
+type TableOutput struct {
+	// mu keeps concurrent renders in the order of their events
+	mu   sync.Mutex
+	out  io.Writer
+	pods *PodTracker
+}
+
+// NewTableOutput returns a TableOutput rendering to out.
+func NewTableOutput(out io.Writer) *TableOutput {
+	return &TableOutput{
+		out:  NewSyncWriter(out),
+		pods: NewPodTracker(),
+	}
+}
+
+// Emit implements Output. Bookmarks and objects other than Pods are ignored.
+func (o *TableOutput) Emit(event Event) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.pods.track(event) {
+		return
+	}
+	// Render into a buffer to clear the screen and draw in a single write
+	var buf bytes.Buffer
+	buf.WriteString(clearScreen)
+	_ = o.pods.WriteTable(&buf)
+	_, _ = o.out.Write(buf.Bytes())
+}
+
+// PodTracker is an Output keeping the current Pods in memory to write them
+// as a table on demand.
+type PodTracker struct {
+	mu   sync.Mutex
+	pods map[string]*v1.Pod
+}
+
+// NewPodTracker returns an empty PodTracker.
+func NewPodTracker() *PodTracker {
+	return &PodTracker{pods: make(map[string]*v1.Pod)}
+}
+
+// Emit implements Output. Bookmarks and objects other than Pods are ignored.
+func (t *PodTracker) Emit(event Event) {
+	t.track(event)
+}
+
+// track records the Pod of event and reports whether the Pods changed.
+func (t *PodTracker) track(event Event) bool {
+	pod, ok := event.Object.(*v1.Pod)
+	if !ok || event.Type == watch.Bookmark {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	key := objectKey(pod)
+	if event.Type == watch.Deleted {
+		delete(t.pods, key)
+	} else {
+		t.pods[key] = pod
+	}
+	return true
+}
+
+// WriteTable writes the Pods sorted by namespace and name to out.
+func (t *PodTracker) WriteTable(out io.Writer) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	keys := make([]string, 0, len(t.pods))
+	for key := range t.pods {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(out, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "NAME\tPHASE\tREADY\tRESTARTS\tAGE")
+	now := time.Now()
+	for _, key := range keys {
+		pod := t.pods[key]
+		ready, restarts := 0, int32(0)
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				ready++
+			}
+			restarts += status.RestartCount
+		}
+		age := duration.HumanDuration(now.Sub(pod.CreationTimestamp.Time))
+		fmt.Fprintf(w, "%s\t%s\t%d/%d\t%d\t%s\n", pod.Name, pod.Status.Phase, ready, len(pod.Spec.Containers), restarts, age)
+	}
+	return w.Flush()
+}
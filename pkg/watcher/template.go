@@ -0,0 +1,39 @@
+package watcher
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"text/template"
+
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// TemplateOutput is an Output executing a text/template against the object
+// of every event, like kubectl -o go-template. Bookmarks are ignored.
+type TemplateOutput struct {
+	logger   *slog.Logger
+	out      io.Writer
+	template *template.Template
+}
+
+// NewTemplateOutput returns a TemplateOutput writing the result of tmpl,
+// followed by a newline, to out. Execution errors are logged to logger.
+func NewTemplateOutput(logger *slog.Logger, out io.Writer, tmpl *template.Template) *TemplateOutput {
+	return &TemplateOutput{logger: logger, out: NewSyncWriter(out), template: tmpl}
+}
+
+// Emit implements Output.
+func (o *TemplateOutput) Emit(event Event) {
+	if event.Type == watch.Bookmark {
+		return
+	}
+	// Only write complete lines, a failing template may stop midway
+	var buf bytes.Buffer
+	if err := o.template.Execute(&buf, event.Object); err != nil {
+		o.logger.Warn("Failed to execute output template", "event_type", event.Type, "error", err)
+		return
+	}
+	buf.WriteByte('\n')
+	_, _ = o.out.Write(buf.Bytes())
+}
@@ -0,0 +1,20 @@
+package watcher
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracer creates the watch spans. It is a no-op until a tracer provider is
+// registered with otel.SetTracerProvider.
+var tracer = otel.Tracer("github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher")
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
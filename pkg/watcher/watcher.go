@@ -0,0 +1,894 @@
+// Package watcher streams Kubernetes objects using watches with
+// sendInitialEvents, dispatching every event to an EventHandler and
+// reconnecting whenever the API server closes the watch.
+package watcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/utils/pointer"
+)
+
+const (
+	// initialBackoff is the delay before the first reconnection attempt.
+	initialBackoff = time.Second
+	// maxBackoff caps the exponential delay between reconnection attempts.
+	maxBackoff = 30 * time.Second
+	// defaultWatchRetries is the number of attempts made to create a watch
+	// before giving up.
+	defaultWatchRetries = 5
+	// decodeErrorWindow is the window within which DecodeErrorThreshold
+	// decode errors restart the watch.
+	decodeErrorWindow = time.Minute
+)
+
+// Options configures what a Watcher watches.
+type Options struct {
+	// Resource is the resource type to watch, e.g. "pods". Defaults to pods.
+	Resource string
+	// LabelSelector restricts the watch to objects matching the labels.
+	LabelSelector string
+	// FieldSelector restricts the watch to objects matching the fields.
+	FieldSelector string
+	// EventType restricts a watch on events to the given type, Normal or
+	// Warning. Empty watches all types.
+	EventType string
+	// SinceResourceVersion starts the watch from this resource version
+	// without an initial list, taking precedence over StateFile. With
+	// ResourceVersionMatch the initial list is served at this version
+	// instead.
+	SinceResourceVersion string
+	// ResourceVersionMatch is how the initial list matches
+	// SinceResourceVersion: NotOlderThan or, for a classic list only,
+	// Exact. Empty skips the initial list when a version is given.
+	ResourceVersionMatch metav1.ResourceVersionMatch
+	// StateFile persists the last bookmarked resource version so a restarted
+	// watch resumes instead of relisting.
+	StateFile string
+	// Verbose logs container statuses for Pod events when using the default
+	// LogHandler.
+	Verbose bool
+	// CacheResync periodically lists the resource and forwards a Deleted
+	// event for every object whose Delete event was missed, pruning it
+	// from the caches of the handlers. Zero disables it.
+	CacheResync time.Duration
+	// ListOnly stops the watch once the initial list is complete, using the
+	// streaming list as a consistent replacement for a LIST.
+	ListOnly bool
+	// ReadyFilter only passes Pods that are ready, ReadyFilterReady, or not
+	// ready, ReadyFilterNotReady. Empty passes all Pods.
+	ReadyFilter string
+	// OnlyPhaseChanges only logs Modified Pod events that change the phase
+	// when using the default LogHandler.
+	OnlyPhaseChanges bool
+	// WatchRetries is the number of attempts made to create the first watch
+	// when the API server is unavailable before Run fails. Once a watch has
+	// been established, Run keeps reconnecting instead. Defaults to 5.
+	WatchRetries int
+	// ClassicList lists the initial state with a LIST request followed by a
+	// watch from its resource version instead of a streaming list.
+	ClassicList bool
+	// DisableBookmarks watches without bookmark events, tracking resource
+	// versions from the other events only, which are saved to StateFile
+	// periodically. Streaming lists need bookmarks, so the initial state is
+	// fetched with a classic LIST instead.
+	DisableBookmarks bool
+	// OnlyNew drops the Added events of the initial list before they reach
+	// Handler, so only the changes after it are shown. ExtraHandlers still
+	// receive them.
+	OnlyNew bool
+	// BufferInitial holds back the initial list and hands it to the handler
+	// sorted by namespace and name once it is complete.
+	BufferInitial bool
+	// WatchTimeout asks the API server to close each watch after this long so
+	// that it is re-established. Zero uses the server default.
+	WatchTimeout time.Duration
+	// BookmarkTimeout is how long a streaming list waits for the
+	// initial-events-end bookmark before assuming the API server does not
+	// send bookmarks and treating the initial list as complete. Zero waits
+	// forever.
+	BookmarkTimeout time.Duration
+	// InitialEventsAnnotation is the annotation marking the bookmark sent
+	// once the initial list has been streamed. Defaults to
+	// metav1.InitialEventsAnnotationKey.
+	InitialEventsAnnotation string
+	// Stats counts the received events. It may be shared between watchers.
+	Stats *Stats
+	// ReconnectJitter randomly extends each reconnection delay by up to this
+	// factor, e.g. 0.5 for up to 50%, so watchers do not reconnect in lockstep.
+	ReconnectJitter float64
+	// DecodeErrorThreshold restarts the watch once this many objects failed
+	// to decode within a minute. Zero never restarts.
+	DecodeErrorThreshold int
+	// PageSize limits the number of objects per request of a classic list,
+	// used with ClassicList or when the API server does not support
+	// sendInitialEvents. Zero lists everything at once.
+	PageSize int
+	// RateLimiter throttles the events passed to the handler. It may be shared
+	// between watchers.
+	RateLimiter *RateLimiter
+	// Benchmark times the processing of every event by the handlers. It may
+	// be shared between watchers.
+	Benchmark *Benchmark
+	// OwnerKind and OwnerName restrict the events to objects owned by the
+	// given workload, e.g. Deployment and nginx.
+	OwnerKind string
+	OwnerName string
+	// Handler receives the events. Defaults to a LogHandler.
+	Handler EventHandler
+	// ExtraHandlers receive every event after Handler, e.g. notifiers,
+	// through a Registry isolating them from each other.
+	ExtraHandlers []EventHandler
+	// Logger is used for the watch lifecycle. Defaults to slog.Default().
+	Logger *slog.Logger
+
+	// ownerFilter is the filter created for OwnerName, bound to the
+	// context of Run
+	ownerFilter *OwnerFilter
+}
+
+// Watcher keeps a watch on one resource type running until its context is
+// cancelled.
+type Watcher struct {
+	clientset kubernetes.Interface
+	// dynamic and gvr are set instead of clientset for custom resources
+	dynamic   dynamic.Interface
+	gvr       schema.GroupVersionResource
+	namespace string
+	options   Options
+	kind      string
+	// events runs the event loop for the object type of the resource
+	events    eventLoop
+	processed atomic.Int64
+	synced    atomic.Bool
+	debug     debugState
+
+	// versions detects resource versions going backwards across events
+	versions resourceVersionTracker
+	// reconnected is set while the first event of a re-established watch is
+	// still outstanding
+	reconnected bool
+	// established is set once a watch has been created, after which
+	// running out of WatchRetries no longer ends Run
+	established bool
+	// listResourceVersion is the version the initial list is served at
+	// with ResourceVersionMatch, until it has been compacted away
+	listResourceVersion string
+	// known holds the names of the objects present for CacheResync, up to
+	// maxKnownObjects, and knownFull is set once that is reached. pruned
+	// holds the objects reported deleted by the last resync, whose real
+	// Delete event is suppressed. resync fires when a resync is due.
+	known     map[types.UID]types.NamespacedName
+	knownFull bool
+	pruned    map[types.UID]bool
+	resync    <-chan time.Time
+	// unsaved is the last resource version of the object events not yet
+	// saved to StateFile with DisableBookmarks, after unsavedEvents events,
+	// and checkpoint fires when it is due to be saved
+	unsaved       string
+	unsavedEvents int
+	checkpoint    <-chan time.Time
+}
+
+// New returns a Watcher for the resource in options within namespace. Use
+// metav1.NamespaceAll to watch every namespace. The namespace is ignored for
+// cluster-scoped resources such as nodes.
+func New(clientset kubernetes.Interface, namespace string, options Options) (*Watcher, error) {
+	if ClusterScoped(options.Resource) {
+		namespace = metav1.NamespaceAll
+	}
+	kind, err := options.complete(clientset, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		clientset: clientset,
+		namespace: namespace,
+		options:   options,
+		kind:      kind,
+		events:    resources[options.Resource].events,
+	}, nil
+}
+
+// NewDynamic returns a Watcher for the custom resource gvr within namespace,
+// streaming *unstructured.Unstructured objects through the dynamic client.
+// The resource in options is replaced by the resource of gvr and logged as
+// the kind. Owner filters are not supported.
+func NewDynamic(client dynamic.Interface, gvr schema.GroupVersionResource, namespace string, options Options) (*Watcher, error) {
+	if options.OwnerName != "" {
+		return nil, errors.New("custom resources do not support owner filters")
+	}
+	options.Resource = gvr.Resource
+	if err := options.completeKind(nil, namespace, gvr.Resource); err != nil {
+		return nil, err
+	}
+	return &Watcher{
+		dynamic:   client,
+		gvr:       gvr,
+		namespace: namespace,
+		options:   options,
+		kind:      gvr.Resource,
+		events:    typedEvents[*unstructured.Unstructured]{},
+	}, nil
+}
+
+// complete validates the options, fills in the defaults and wraps the
+// handler as requested. It returns the kind of the watched resource.
+func (o *Options) complete(clientset kubernetes.Interface, namespace string) (string, error) {
+	if o.Resource == "" {
+		o.Resource = "pods"
+	}
+	info, err := lookupResource(o.Resource)
+	if err != nil {
+		return "", err
+	}
+	return info.kind, o.completeKind(clientset, namespace, info.kind)
+}
+
+// completeKind completes the options for a resource of the given kind.
+func (o *Options) completeKind(clientset kubernetes.Interface, namespace, kind string) error {
+	if o.EventType != "" && o.Resource == "events" {
+		if o.EventType != v1.EventTypeNormal && o.EventType != v1.EventTypeWarning {
+			return fmt.Errorf("unsupported event type %q", o.EventType)
+		}
+		selector := fields.OneTermEqualSelector("type", o.EventType)
+		if o.FieldSelector != "" {
+			parsed, err := fields.ParseSelector(o.FieldSelector)
+			if err != nil {
+				return fmt.Errorf("invalid field selector: %w", err)
+			}
+			selector = fields.AndSelectors(parsed, selector)
+		}
+		o.FieldSelector = selector.String()
+	}
+	if o.ReadyFilter != "" && o.ReadyFilter != ReadyFilterReady && o.ReadyFilter != ReadyFilterNotReady {
+		return fmt.Errorf("unsupported ready filter %q", o.ReadyFilter)
+	}
+	if o.ListOnly && o.SinceResourceVersion != "" && o.ResourceVersionMatch == "" {
+		return errors.New("listing only needs an initial list, which a resource version without resourceVersionMatch skips")
+	}
+	if err := o.validateResourceVersionMatch(); err != nil {
+		return err
+	}
+	if o.WatchRetries <= 0 {
+		o.WatchRetries = defaultWatchRetries
+	}
+	if o.InitialEventsAnnotation == "" {
+		o.InitialEventsAnnotation = metav1.InitialEventsAnnotationKey
+	}
+	if o.Logger == nil {
+		o.Logger = slog.Default()
+	}
+	// Tell the output of concurrent watchers apart
+	o.Logger = o.Logger.With("kind", kind)
+	if o.DisableBookmarks {
+		o.Logger.Warn("Bookmarks disabled, resource versions are only tracked from object events, so resuming after a reconnect or restart is less reliable and may need a relist")
+		if !o.ClassicList {
+			o.Logger.Info("Streaming lists require bookmarks, using a classic LIST for the initial state")
+			o.ClassicList = true
+		}
+	}
+	if o.Handler == nil {
+		o.Handler = NewLogHandler(o.Logger, kind, LogOptions{
+			AllNamespaces:    namespace == metav1.NamespaceAll,
+			Verbose:          o.Verbose,
+			OnlyPhaseChanges: o.OnlyPhaseChanges,
+		})
+	}
+	if o.OnlyNew {
+		o.Handler = NewOnlyNewFilter(o.Handler)
+	}
+	if o.RateLimiter != nil {
+		o.Handler = o.RateLimiter.Wrap(o.Handler)
+	}
+	if len(o.ExtraHandlers) > 0 {
+		registry := NewRegistry(o.Logger)
+		registry.Register(o.Handler)
+		for _, h := range o.ExtraHandlers {
+			registry.Register(h)
+		}
+		o.Handler = registry
+	}
+	if o.OwnerName != "" {
+		o.ownerFilter = NewOwnerFilter(clientset, o.Logger, o.OwnerKind, o.OwnerName, o.Handler)
+		o.Handler = o.ownerFilter
+	}
+	if o.ReadyFilter != "" {
+		o.Handler = NewReadyFilter(o.ReadyFilter == ReadyFilterReady, o.Handler)
+	}
+	if o.BufferInitial {
+		o.Handler = NewBufferingHandler(o.Handler)
+	}
+	if o.Benchmark != nil {
+		o.Handler = o.Benchmark.Wrap(o.Handler)
+	}
+	return nil
+}
+
+// validateResourceVersionMatch checks ResourceVersionMatch against the
+// constraints of the API server, which only accepts NotOlderThan along with
+// sendInitialEvents and requires a resource version to list at otherwise.
+func (o *Options) validateResourceVersionMatch() error {
+	switch o.ResourceVersionMatch {
+	case "":
+		return nil
+	case metav1.ResourceVersionMatchNotOlderThan:
+		if o.ClassicList && o.SinceResourceVersion == "" {
+			return errors.New("resourceVersionMatch NotOlderThan requires a resource version to list at")
+		}
+		return nil
+	case metav1.ResourceVersionMatchExact:
+		if !o.ClassicList {
+			return errors.New("resourceVersionMatch Exact cannot be combined with sendInitialEvents, which requires NotOlderThan; disable the streaming list")
+		}
+		if o.SinceResourceVersion == "" || o.SinceResourceVersion == "0" {
+			return errors.New("resourceVersionMatch Exact requires a non-zero resource version to list at")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported resourceVersionMatch %q, expected NotOlderThan or Exact", o.ResourceVersionMatch)
+	}
+}
+
+// Processed returns the number of events received so far.
+func (w *Watcher) Processed() int {
+	return int(w.processed.Load())
+}
+
+// Synced reports whether the initial list has been received, i.e. the
+// initial-events-end bookmark has been seen or the watch resumed from a known
+// resource version.
+func (w *Watcher) Synced() bool {
+	return w.synced.Load()
+}
+
+// State returns a snapshot of the internal state of the watch.
+func (w *Watcher) State() State {
+	state := State{
+		Resource:  w.options.Resource,
+		Namespace: w.namespace,
+		Processed: w.Processed(),
+		Synced:    w.Synced(),
+	}
+	w.debug.fill(&state)
+	return state
+}
+
+// Run watches until ctx is cancelled, reconnecting with exponential backoff
+// whenever the watch is closed or fails with a transient error. It returns nil
+// once ctx is cancelled and an error when the API server rejects the watch
+// permanently, for example because of missing permissions.
+func (w *Watcher) Run(ctx context.Context) (err error) {
+	logger := w.options.Logger
+	logger.Info("Starting to watch", "resource", w.options.Resource, "namespace", w.namespace, "selector", w.options.LabelSelector, "field_selector", w.options.FieldSelector)
+
+	// Trace the whole watch with a child span per session
+	ctx, span := tracer.Start(ctx, "watch", trace.WithAttributes(
+		attribute.String("resource", w.options.Resource),
+		attribute.String("namespace", w.namespace),
+	))
+	defer func() {
+		state := w.State()
+		span.SetAttributes(
+			attribute.Int("events", state.Processed),
+			attribute.Int("reconnects", state.Reconnects),
+			attribute.String("resource_version", state.ResourceVersion),
+		)
+		endSpan(span, err)
+	}()
+
+	if w.options.ownerFilter != nil {
+		w.options.ownerFilter.bind(ctx)
+	}
+
+	// Resume from the requested version or the bookmark saved by a previous
+	// run, if any
+	resourceVersion, err := loadResourceVersion(w.options.StateFile)
+	switch {
+	case w.options.SinceResourceVersion != "" && w.options.ResourceVersionMatch != "":
+		resourceVersion = ""
+		w.listResourceVersion = w.options.SinceResourceVersion
+		logger.Info("Starting with the initial list at the requested resource version", "resource_version", w.listResourceVersion, "resource_version_match", w.options.ResourceVersionMatch)
+	case w.options.SinceResourceVersion != "":
+		resourceVersion = w.options.SinceResourceVersion
+		logger.Info("Starting from the requested resource version, skipping the initial list", "resource_version", resourceVersion)
+	case w.options.ListOnly:
+		resourceVersion = ""
+		logger.Info("Listing once, stopping after the initial list")
+	case err != nil:
+		return fmt.Errorf("reading state file: %w", err)
+	case resourceVersion != "":
+		logger.Info("Resuming from saved resource version", "resource_version", resourceVersion)
+	default:
+		logger.Info("Starting with the initial list")
+	}
+
+	// Prune the objects whose Delete event was missed from time to time
+	if w.options.CacheResync > 0 {
+		ticker := time.NewTicker(w.options.CacheResync)
+		defer ticker.Stop()
+		w.known = make(map[types.UID]types.NamespacedName)
+		w.pruned = make(map[types.UID]bool)
+		w.resync = ticker.C
+	}
+
+	// Without bookmarks, save the version of the object events now and then
+	// rather than on every event, and once more when stopping
+	if w.options.DisableBookmarks && w.options.StateFile != "" {
+		ticker := time.NewTicker(checkpointInterval)
+		defer ticker.Stop()
+		w.checkpoint = ticker.C
+		defer w.saveCheckpoint()
+	}
+
+	backoff := initialBackoff
+	for {
+		sessionCtx, session := tracer.Start(ctx, "watch.session", trace.WithAttributes(
+			attribute.String("namespace", w.namespace),
+			attribute.String("resource_version", resourceVersion),
+		))
+		lastResourceVersion, received, err := w.watchEvents(sessionCtx, resourceVersion)
+		session.SetAttributes(
+			attribute.Int("events", received),
+			attribute.String("last_resource_version", lastResourceVersion),
+		)
+		endSpan(session, err)
+		w.processed.Add(int64(received))
+		if lastResourceVersion != "" {
+			resourceVersion = lastResourceVersion
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		if w.options.ListOnly && err == nil && w.Synced() {
+			logger.Info("Initial list complete, stopping", "resource_version", resourceVersion)
+			return nil
+		}
+		if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+			// The saved version has been compacted away, start over with a full list
+			logger.Warn("Resource version is too old, starting fresh", "resource_version", resourceVersion, "error", err)
+			resourceVersion = ""
+			w.listResourceVersion = ""
+			w.unsaved, w.unsavedEvents = "", 0
+			if err := saveResourceVersion(w.options.StateFile, ""); err != nil {
+				logger.Warn("Failed to clear state file", "path", w.options.StateFile, "error", err)
+			}
+		} else if isPermanent(err) || (errors.Is(err, errRetriesExhausted) && !w.established) {
+			// Give up at startup only, an outage later is waited out
+			return fmt.Errorf("watching %s: %w", w.options.Resource, err)
+		} else if err != nil {
+			logger.Error("Watch failed, will retry", "error", err)
+		}
+
+		// Only keep backing off while watches fail to deliver anything
+		if received > 0 {
+			backoff = initialBackoff
+		}
+		// Spread out the reconnects of many watchers
+		sleep := backoff
+		if w.options.ReconnectJitter > 0 {
+			sleep = wait.Jitter(backoff, w.options.ReconnectJitter)
+		}
+		logger.Info("Watch closed, reconnecting", "backoff", sleep, "resource_version", resourceVersion)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(sleep):
+		}
+		reconnectsTotal.Inc()
+		w.debug.reconnect()
+		w.reconnected = true
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// watchEvents runs a single watch session until the API server closes the
+// result channel or ctx is cancelled. An empty resourceVersion requests the
+// initial list, either via sendInitialEvents or a classic LIST, otherwise the
+// watch resumes from that version. It returns the last observed resource
+// version, the number of events received and the error that ended the
+// session, if any.
+func (w *Watcher) watchEvents(ctx context.Context, resourceVersion string) (string, int, error) {
+	logger := w.options.Logger
+
+	received := 0
+	watchOptions := metav1.ListOptions{
+		AllowWatchBookmarks: !w.options.DisableBookmarks,
+		LabelSelector:       w.options.LabelSelector,
+		FieldSelector:       w.options.FieldSelector,
+	}
+	switch {
+	case resourceVersion == "" && w.options.ClassicList:
+		// Classic LIST+WATCH: list the current state, then watch from the
+		// version of the list
+		listResourceVersion, listed, err := w.listInitial(ctx)
+		received += listed
+		if err != nil {
+			return resourceVersion, received, fmt.Errorf("listing: %w", classify(err))
+		}
+		resourceVersion = listResourceVersion
+		if w.options.ListOnly {
+			return resourceVersion, received, nil
+		}
+		watchOptions.ResourceVersion = resourceVersion
+	case resourceVersion == "":
+		// Streaming list: request the initial list via watch
+		watchOptions.SendInitialEvents = pointer.Bool(true)
+		watchOptions.ResourceVersionMatch = metav1.ResourceVersionMatchNotOlderThan
+		watchOptions.ResourceVersion = w.listResourceVersion
+	case w.options.ClassicList:
+		// Resume from the last observed version instead of relisting
+		watchOptions.ResourceVersion = resourceVersion
+	default:
+		// Resume from the last observed version instead of relisting
+		watchOptions.SendInitialEvents = pointer.Bool(false)
+		watchOptions.ResourceVersionMatch = metav1.ResourceVersionMatchNotOlderThan
+		watchOptions.ResourceVersion = resourceVersion
+	}
+	// Let the API server close the watch periodically
+	if w.options.WatchTimeout > 0 {
+		watchOptions.TimeoutSeconds = pointer.Int64(int64(w.options.WatchTimeout.Seconds()))
+	}
+	logger.Info("Creating watch", "options", fmt.Sprintf("%+v", watchOptions))
+
+	watcher, err := w.createWatch(ctx, watchOptions)
+	if err != nil && watchOptions.SendInitialEvents != nil && isSendInitialEventsUnsupported(err) {
+		// Older API servers reject the streaming list, list in pages instead
+		logger.Warn("API server does not support sendInitialEvents, falling back to a paginated list", "page_size", w.options.PageSize, "error", err)
+		w.options.ClassicList = true
+		return w.watchEvents(ctx, resourceVersion)
+	}
+	if err != nil {
+		return resourceVersion, received, fmt.Errorf("creating watch: %w", classify(err))
+	}
+	defer watcher.Stop()
+	w.established = true
+
+	// Without sendInitialEvents there is no initial list to wait for. Tell
+	// the handlers holding back the initial list once, as after a list
+	if watchOptions.SendInitialEvents == nil || !*watchOptions.SendInitialEvents {
+		if !w.synced.Swap(true) {
+			w.options.Handler.OnBookmark(initialEventsEndBookmark(w.options.InitialEventsAnnotation, resourceVersion), true)
+		}
+	}
+
+	// Process the watch events
+	inInitialList := watchOptions.SendInitialEvents != nil && *watchOptions.SendInitialEvents
+	resourceVersion, processed, err := w.events.process(w, ctx, watcher.ResultChan(), resourceVersion, inInitialList)
+	return resourceVersion, received + processed, err
+}
+
+// eventLoop runs processEvents for the object type of a resource.
+type eventLoop interface {
+	process(w *Watcher, ctx context.Context, events <-chan watch.Event, resourceVersion string, inInitialList bool) (string, int, error)
+}
+
+// typedEvents is the eventLoop of a resource whose objects are of type T.
+type typedEvents[T metav1.Object] struct{}
+
+func (typedEvents[T]) process(w *Watcher, ctx context.Context, events <-chan watch.Event, resourceVersion string, inInitialList bool) (string, int, error) {
+	return processEvents[T](w, ctx, events, resourceVersion, inInitialList)
+}
+
+// processEvents dispatches the events of a watch session, whose objects are
+// of type T, to the handler until the channel is closed, an error event
+// arrives or ctx is done, which takes effect immediately even while no
+// events arrive. Objects of another type are counted as decode errors. The
+// metadata is read from T directly, and the type-specific rendering is left
+// to the describers of the handlers. While inInitialList is set resource
+// versions are only tracked once the initial-events-end bookmark arrives. It
+// returns the last resource version and the number of events received.
+func processEvents[T metav1.Object](w *Watcher, ctx context.Context, events <-chan watch.Event, resourceVersion string, inInitialList bool) (string, int, error) {
+	logger := w.options.Logger
+	handler := w.options.Handler
+
+	// Give up on the initial-events-end bookmark if it does not arrive in time
+	var bookmarkTimeout <-chan time.Time
+	if inInitialList && w.options.BookmarkTimeout > 0 {
+		timer := time.NewTimer(w.options.BookmarkTimeout)
+		defer timer.Stop()
+		bookmarkTimeout = timer.C
+	}
+
+	decodeErrors := errorWindow{threshold: w.options.DecodeErrorThreshold, window: decodeErrorWindow}
+	received := 0
+	for {
+		var event watch.Event
+		select {
+		case <-ctx.Done():
+			// Return right away, the deferred Stop closes the watch
+			return resourceVersion, received, nil
+		case <-bookmarkTimeout:
+			bookmarkTimeout = nil
+			if inInitialList {
+				logger.Warn("No initial-events-end bookmark received, bookmarks appear unsupported, treating the initial list as complete", "timeout", w.options.BookmarkTimeout)
+				inInitialList = false
+				w.synced.Store(true)
+				handler.OnBookmark(initialEventsEndBookmark(w.options.InitialEventsAnnotation, resourceVersion), true)
+				if w.options.ListOnly {
+					return resourceVersion, received, nil
+				}
+			}
+			continue
+		case <-w.resync:
+			w.pruneStale(ctx)
+			continue
+		case <-w.checkpoint:
+			w.saveCheckpoint()
+			continue
+		case e, ok := <-events:
+			if !ok {
+				return resourceVersion, received, nil
+			}
+			event = e
+		}
+		received++
+		recordEvent(event.Type)
+		w.options.Stats.observe(event.Type)
+
+		// Error events carry a Status rather than an object with metadata.
+		// End the session so Run can relist on 410 Gone or reconnect.
+		if event.Type == watch.Error {
+			status, ok := event.Object.(*metav1.Status)
+			if !ok {
+				logger.Error("Error event received", "event_type", event.Type, "object_type", fmt.Sprintf("%T", event.Object))
+				return resourceVersion, received, fmt.Errorf("watch error event with unexpected object %T", event.Object)
+			}
+			logger.Error("Error event received", "event_type", event.Type, "code", status.Code, "reason", status.Reason, "message", status.Message)
+			return resourceVersion, received, fmt.Errorf("watch error event: %w", &apierrors.StatusError{ErrStatus: *status})
+		}
+
+		obj, ok := event.Object.(T)
+		if !ok {
+			var gvk schema.GroupVersionKind
+			if event.Object != nil {
+				gvk = event.Object.GetObjectKind().GroupVersionKind()
+			}
+			logger.Warn("Received object of an unexpected type", "event_type", event.Type, "object_type", fmt.Sprintf("%T", event.Object), "expected_type", fmt.Sprintf("%T", *new(T)), "gvk", gvk.String())
+			decodeErrorsTotal.Inc()
+
+			// Persistent failures usually mean a stale connection, restart
+			if decodeErrors.observe(time.Now()) {
+				return resourceVersion, received, fmt.Errorf("%d decode errors within %s", w.options.DecodeErrorThreshold, decodeErrorWindow)
+			}
+			continue
+		}
+		// Objects of the initial list arrive in no particular version order,
+		// only track versions once the list is complete
+		initialEventsEnd := event.Type == watch.Bookmark && obj.GetAnnotations()[w.options.InitialEventsAnnotation] == "true"
+		if initialEventsEnd {
+			inInitialList = false
+			logger.Info("Initial sync complete", "annotation", w.options.InitialEventsAnnotation, "resource_version", obj.GetResourceVersion())
+		}
+		if !inInitialList {
+			resourceVersion = obj.GetResourceVersion()
+			w.checkResourceVersion(event.Type, obj)
+		}
+		w.debug.observe(event.Type, resourceVersion)
+
+		// Without bookmarks the object events are the only checkpoints
+		if w.checkpoint != nil && !inInitialList && event.Type != watch.Bookmark {
+			w.noteCheckpoint(resourceVersion)
+		}
+
+		// Dispatch the object based on the event type
+		switch event.Type {
+		case watch.Added:
+			w.track(event.Object)
+			handler.OnAdded(event.Object)
+		case watch.Modified:
+			w.track(event.Object)
+			handler.OnModified(event.Object)
+		case watch.Deleted:
+			if w.untrack(event.Object) {
+				handler.OnDeleted(event.Object)
+			}
+		case watch.Bookmark:
+			if err := saveResourceVersion(w.options.StateFile, resourceVersion); err != nil {
+				logger.Warn("Failed to write state file", "path", w.options.StateFile, "error", err)
+			}
+			if initialEventsEnd {
+				w.synced.Store(true)
+			}
+			handler.OnBookmark(event.Object, initialEventsEnd)
+			// Stop right away instead of waiting for further events
+			if initialEventsEnd && w.options.ListOnly {
+				return resourceVersion, received, nil
+			}
+		default:
+			logger.Warn("Unknown event type", "event_type", event.Type, "name", obj.GetName())
+		}
+	}
+}
+
+// checkResourceVersion warns when the resource version of obj is not newer
+// than the last one processed, which indicates a relist or out-of-order
+// delivery. Bookmarks may repeat the last version.
+func (w *Watcher) checkResourceVersion(eventType watch.EventType, obj metav1.Object) {
+	reconnected := w.reconnected
+	w.reconnected = false
+
+	previous, regressed := w.versions.observe(obj.GetResourceVersion(), eventType == watch.Bookmark)
+	if !regressed {
+		return
+	}
+	attrs := []any{"event_type", eventType, "name", obj.GetName(), "resource_version", obj.GetResourceVersion(), "previous_resource_version", previous}
+	if reconnected {
+		w.options.Logger.Warn("Resource version went backwards after reconnecting, a fresh list occurred", attrs...)
+		return
+	}
+	w.options.Logger.Warn("Resource version did not increase, possible relist or out-of-order delivery", attrs...)
+}
+
+// listInitial lists the current objects with classic LIST requests, in pages
+// of PageSize objects if set, and reports each of them to the handler as
+// added, followed by a synthetic initial-events-end bookmark. It returns the
+// resource version of the list and the number of objects listed.
+func (w *Watcher) listInitial(ctx context.Context) (string, int, error) {
+	opts := metav1.ListOptions{
+		LabelSelector: w.options.LabelSelector,
+		FieldSelector: w.options.FieldSelector,
+		Limit:         int64(w.options.PageSize),
+	}
+	if w.listResourceVersion != "" {
+		opts.ResourceVersion = w.listResourceVersion
+		opts.ResourceVersionMatch = w.options.ResourceVersionMatch
+		if opts.ResourceVersionMatch == "" {
+			opts.ResourceVersionMatch = metav1.ResourceVersionMatchNotOlderThan
+		}
+	}
+	var resourceVersion string
+	listed, pages := 0, 0
+	for {
+		list, err := w.list(ctx, opts)
+		if err != nil {
+			return "", listed, err
+		}
+		listAccessor, err := meta.ListAccessor(list)
+		if err != nil {
+			return "", listed, err
+		}
+		items, err := meta.ExtractList(list)
+		if err != nil {
+			return "", listed, err
+		}
+		for _, item := range items {
+			recordEvent(watch.Added)
+			w.options.Stats.observe(watch.Added)
+			w.debug.observe(watch.Added, "")
+			w.track(item)
+			w.options.Handler.OnAdded(item)
+		}
+		listed += len(items)
+		pages++
+
+		// Pages after the first are served from the version of the first
+		resourceVersion = listAccessor.GetResourceVersion()
+		if listAccessor.GetContinue() == "" {
+			break
+		}
+		// The continue token carries the version, which may not be repeated
+		opts.Continue = listAccessor.GetContinue()
+		opts.ResourceVersion = ""
+		opts.ResourceVersionMatch = ""
+	}
+	w.options.Logger.Info("Listed initial objects", "items", listed, "pages", pages, "resource_version", resourceVersion)
+
+	// Signal the end of the initial list the same way a streaming list does
+	w.synced.Store(true)
+	w.options.Handler.OnBookmark(initialEventsEndBookmark(w.options.InitialEventsAnnotation, resourceVersion), true)
+	return resourceVersion, listed, nil
+}
+
+// initialEventsEndBookmark returns a synthetic bookmark object carrying
+// annotation, marking the end of an initial list obtained without a
+// streaming list.
+func initialEventsEndBookmark(annotation, resourceVersion string) runtime.Object {
+	return &metav1.PartialObjectMetadata{
+		ObjectMeta: metav1.ObjectMeta{
+			ResourceVersion: resourceVersion,
+			Annotations:     map[string]string{annotation: "true"},
+		},
+	}
+}
+
+// errRetriesExhausted is returned when the watch could not be created within
+// the configured number of attempts.
+var errRetriesExhausted = errors.New("watch creation retries exhausted")
+
+// createWatch starts the watch, retrying transient failures such as an
+// unreachable API server with exponential backoff.
+func (w *Watcher) createWatch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	backoff := wait.Backoff{
+		Duration: initialBackoff,
+		Factor:   2,
+		Steps:    w.options.WatchRetries,
+		Cap:      maxBackoff,
+		Jitter:   w.options.ReconnectJitter,
+	}
+
+	var watcher watch.Interface
+	var lastErr error
+	attempt := 0
+	err := wait.ExponentialBackoffWithContext(ctx, backoff, func(ctx context.Context) (bool, error) {
+		attempt++
+		var err error
+		watcher, err = w.watch(ctx, opts)
+		if err == nil {
+			return true, nil
+		}
+		// Retrying cannot fix these, let Run decide what to do
+		if isPermanent(err) || apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+			return false, err
+		}
+		lastErr = err
+		w.options.Logger.Warn("Failed to create watch", "attempt", attempt, "max_attempts", w.options.WatchRetries, "error", err)
+		return false, nil
+	})
+	if err != nil && lastErr != nil && ctx.Err() == nil && wait.Interrupted(err) {
+		return nil, fmt.Errorf("%w after %d attempts: %w", errRetriesExhausted, attempt, lastErr)
+	}
+	return watcher, err
+}
+
+// watch starts a watch on the resource through the typed or dynamic client.
+func (w *Watcher) watch(ctx context.Context, opts metav1.ListOptions) (watch.Interface, error) {
+	if w.dynamic != nil {
+		return w.dynamic.Resource(w.gvr).Namespace(w.namespace).Watch(ctx, opts)
+	}
+	return watchResource(ctx, w.clientset, w.options.Resource, w.namespace, opts)
+}
+
+// list lists the resource through the typed or dynamic client.
+func (w *Watcher) list(ctx context.Context, opts metav1.ListOptions) (runtime.Object, error) {
+	if w.dynamic != nil {
+		return w.dynamic.Resource(w.gvr).Namespace(w.namespace).List(ctx, opts)
+	}
+	return listResource(ctx, w.clientset, w.options.Resource, w.namespace, opts)
+}
+
+// isSendInitialEventsUnsupported reports whether err is the API server
+// rejecting the sendInitialEvents parameter of a watch.
+func isSendInitialEventsUnsupported(err error) bool {
+	var status apierrors.APIStatus
+	if !errors.As(err, &status) {
+		return false
+	}
+	reason := status.Status().Reason
+	if reason != metav1.StatusReasonBadRequest && reason != metav1.StatusReasonInvalid {
+		return false
+	}
+	return strings.Contains(strings.ToLower(status.Status().Message), "sendinitialevents")
+}
+
+// isPermanent reports whether err is an API error that retrying the watch
+// cannot fix.
+func isPermanent(err error) bool {
+	return apierrors.IsForbidden(err) ||
+		apierrors.IsUnauthorized(err) ||
+		apierrors.IsNotFound(err) ||
+		apierrors.IsBadRequest(err) ||
+		apierrors.IsInvalid(err) ||
+		apierrors.IsMethodNotSupported(err)
+}
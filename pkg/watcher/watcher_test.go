@@ -0,0 +1,242 @@
+package watcher
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"testing"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+// recordedEvent is a callback received by a recordingHandler.
+type recordedEvent struct {
+	eventType        watch.EventType
+	name             string
+	initialEventsEnd bool
+}
+
+// recordingHandler is an EventHandler sending each of its callbacks on
+// events.
+type recordingHandler struct {
+	events chan recordedEvent
+}
+
+func newRecordingHandler() *recordingHandler {
+	return &recordingHandler{events: make(chan recordedEvent, 100)}
+}
+
+func (h *recordingHandler) record(eventType watch.EventType, obj runtime.Object, initialEventsEnd bool) {
+	event := recordedEvent{eventType: eventType, initialEventsEnd: initialEventsEnd}
+	if accessor, err := meta.Accessor(obj); err == nil {
+		event.name = accessor.GetName()
+	}
+	h.events <- event
+}
+
+func (h *recordingHandler) OnAdded(obj runtime.Object)    { h.record(watch.Added, obj, false) }
+func (h *recordingHandler) OnModified(obj runtime.Object) { h.record(watch.Modified, obj, false) }
+func (h *recordingHandler) OnDeleted(obj runtime.Object)  { h.record(watch.Deleted, obj, false) }
+func (h *recordingHandler) OnBookmark(obj runtime.Object, initialEventsEnd bool) {
+	h.record(watch.Bookmark, obj, initialEventsEnd)
+}
+
+// wait returns the next recorded callback, failing the test after a while.
+func (h *recordingHandler) wait(t *testing.T) recordedEvent {
+	t.Helper()
+	select {
+	case event := <-h.events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for a handler callback")
+		return recordedEvent{}
+	}
+}
+
+func testPod(name, resourceVersion string, phase v1.PodPhase) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", ResourceVersion: resourceVersion, UID: types.UID("uid-" + name)},
+		Status:     v1.PodStatus{Phase: phase},
+	}
+}
+
+func discardLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func TestWatcherDispatchesEvents(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	fakeWatch := watch.NewFake()
+	clientset.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatch, nil
+	})
+
+	handler := newRecordingHandler()
+	w, err := New(clientset, "default", Options{Handler: handler, Logger: discardLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	fakeWatch.Add(testPod("web", "1", v1.PodPending))
+	fakeWatch.Modify(testPod("web", "2", v1.PodRunning))
+	fakeWatch.Delete(testPod("web", "3", v1.PodRunning))
+
+	want := []recordedEvent{
+		{eventType: watch.Added, name: "web"},
+		{eventType: watch.Modified, name: "web"},
+		{eventType: watch.Deleted, name: "web"},
+	}
+	for _, expected := range want {
+		if got := handler.wait(t); got != expected {
+			t.Errorf("got callback %+v, want %+v", got, expected)
+		}
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run returned %v after cancellation, want nil", err)
+	}
+	if got := w.Processed(); got != len(want) {
+		t.Errorf("Processed() = %d, want %d", got, len(want))
+	}
+}
+
+func TestProcessEventsDetectsInitialEventsEnd(t *testing.T) {
+	clientset := fake.NewSimpleClientset()
+	handler := newRecordingHandler()
+	w, err := New(clientset, "default", Options{Handler: handler, Logger: discardLogger()})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fakeWatch := watch.NewFake()
+	type result struct {
+		resourceVersion string
+		received        int
+		err             error
+	}
+	done := make(chan result, 1)
+	go func() {
+		resourceVersion, received, err := processEvents[*v1.Pod](w, context.Background(), fakeWatch.ResultChan(), "", true)
+		done <- result{resourceVersion, received, err}
+	}()
+
+	// An unannotated bookmark does not end the initial list, and an object
+	// of another type is skipped
+	fakeWatch.Add(testPod("web", "5", v1.PodPending))
+	fakeWatch.Add(&v1.Service{ObjectMeta: metav1.ObjectMeta{Name: "web", ResourceVersion: "5"}})
+	fakeWatch.Action(watch.Bookmark, &v1.Pod{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "6"}})
+	if event := handler.wait(t); event.eventType != watch.Added {
+		t.Fatalf("got callback %+v, want Added", event)
+	}
+	if event := handler.wait(t); event.eventType != watch.Bookmark || event.initialEventsEnd {
+		t.Fatalf("got callback %+v, want a bookmark not ending the initial list", event)
+	}
+	if w.Synced() {
+		t.Fatal("Synced() = true before the initial-events-end bookmark")
+	}
+
+	fakeWatch.Action(watch.Bookmark, &v1.Pod{ObjectMeta: metav1.ObjectMeta{
+		ResourceVersion: "7",
+		Annotations:     map[string]string{metav1.InitialEventsAnnotationKey: "true"},
+	}})
+	if event := handler.wait(t); event.eventType != watch.Bookmark || !event.initialEventsEnd {
+		t.Fatalf("got callback %+v, want the initial-events-end bookmark", event)
+	}
+	if !w.Synced() {
+		t.Fatal("Synced() = false after the initial-events-end bookmark")
+	}
+
+	fakeWatch.Modify(testPod("web", "8", v1.PodRunning))
+	fakeWatch.Delete(testPod("web", "9", v1.PodRunning))
+	for _, want := range []watch.EventType{watch.Modified, watch.Deleted} {
+		if event := handler.wait(t); event.eventType != want || event.name != "web" {
+			t.Fatalf("got callback %+v, want %s of web", event, want)
+		}
+	}
+
+	fakeWatch.Stop()
+	got := <-done
+	if got.err != nil {
+		t.Fatalf("processEvents returned %v", got.err)
+	}
+	if got.received != 6 {
+		t.Errorf("received %d events, want 6", got.received)
+	}
+	if got.resourceVersion != "9" {
+		t.Errorf("last resource version %q, want 9", got.resourceVersion)
+	}
+}
+
+// runResumed runs a Watcher with options resuming from a saved resource
+// version and returns the handler receiving its callbacks and the watch
+// feeding it. The Watcher stops when the test ends.
+func runResumed(t *testing.T, options Options) (*recordingHandler, *watch.FakeWatcher) {
+	t.Helper()
+	options.StateFile = filepath.Join(t.TempDir(), "state.json")
+	if err := saveResourceVersion(options.StateFile, "10"); err != nil {
+		t.Fatal(err)
+	}
+	clientset := fake.NewSimpleClientset()
+	fakeWatch := watch.NewFake()
+	clientset.PrependWatchReactor("pods", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		return true, fakeWatch, nil
+	})
+
+	handler := newRecordingHandler()
+	options.Handler = handler
+	options.Logger = discardLogger()
+	w, err := New(clientset, "default", options)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	// A resumed watch has no initial list, which ends right away
+	if event := handler.wait(t); event.eventType != watch.Bookmark || !event.initialEventsEnd {
+		t.Fatalf("got callback %+v, want the initial-events-end bookmark", event)
+	}
+	if !w.Synced() {
+		t.Fatal("Synced() = false after resuming")
+	}
+	return handler, fakeWatch
+}
+
+func TestOnlyNewPassesAddedEventsAfterResume(t *testing.T) {
+	handler, fakeWatch := runResumed(t, Options{OnlyNew: true})
+
+	fakeWatch.Add(testPod("web", "11", v1.PodPending))
+	fakeWatch.Modify(testPod("web", "12", v1.PodRunning))
+	for _, want := range []watch.EventType{watch.Added, watch.Modified} {
+		if event := handler.wait(t); event.eventType != want || event.name != "web" {
+			t.Fatalf("got callback %+v, want %s of web", event, want)
+		}
+	}
+}
+
+func TestBufferInitialForwardsAddedEventsAfterResume(t *testing.T) {
+	handler, fakeWatch := runResumed(t, Options{BufferInitial: true})
+
+	fakeWatch.Add(testPod("web", "11", v1.PodPending))
+	if event := handler.wait(t); event.eventType != watch.Added || event.name != "web" {
+		t.Fatalf("got callback %+v, want Added of web", event)
+	}
+}
@@ -0,0 +1,129 @@
+package watcher
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+const (
+	// webhookTimeout bounds each webhook delivery.
+	webhookTimeout = 5 * time.Second
+	// webhookQueueSize is how many notifications may wait for delivery before
+	// new ones are dropped.
+	webhookQueueSize = 100
+	// webhookFailureThreshold is how many consecutive deliveries may fail
+	// before notifications are skipped for webhookCooldown.
+	webhookFailureThreshold = 5
+	webhookCooldown         = 30 * time.Second
+)
+
+// deletionNotification is the payload posted for a deleted Pod.
+type deletionNotification struct {
+	Namespace string    `json:"namespace"`
+	Name      string    `json:"name"`
+	DeletedAt time.Time `json:"deletedAt"`
+}
+
+// WebhookNotifier is an EventHandler posting a JSON notification to a URL
+// for every deleted Pod. Deliveries happen in the background so slow
+// webhooks do not stall the watch, and stop for a while when the webhook
+// keeps failing.
+type WebhookNotifier struct {
+	url     string
+	client  *http.Client
+	logger  *slog.Logger
+	queue   chan deletionNotification
+	breaker *circuitBreaker
+}
+
+// NewWebhookNotifier returns a WebhookNotifier posting to url. Run must be
+// called to deliver the notifications.
+func NewWebhookNotifier(logger *slog.Logger, url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:     url,
+		client:  &http.Client{Timeout: webhookTimeout},
+		logger:  logger,
+		queue:   make(chan deletionNotification, webhookQueueSize),
+		breaker: newCircuitBreaker(logger.With("url", url), webhookFailureThreshold, webhookCooldown),
+	}
+}
+
+// OnAdded implements EventHandler.
+func (n *WebhookNotifier) OnAdded(obj runtime.Object) {}
+
+// OnModified implements EventHandler.
+func (n *WebhookNotifier) OnModified(obj runtime.Object) {}
+
+// OnDeleted implements EventHandler.
+func (n *WebhookNotifier) OnDeleted(obj runtime.Object) {
+	pod, ok := obj.(*v1.Pod)
+	if !ok {
+		return
+	}
+	notification := deletionNotification{
+		Namespace: pod.Namespace,
+		Name:      pod.Name,
+		DeletedAt: time.Now().UTC(),
+	}
+	if pod.DeletionTimestamp != nil {
+		notification.DeletedAt = pod.DeletionTimestamp.UTC()
+	}
+	select {
+	case n.queue <- notification:
+	default:
+		n.logger.Warn("Webhook queue full, dropping notification", "namespace", pod.Namespace, "pod_name", pod.Name)
+	}
+}
+
+// OnBookmark implements EventHandler.
+func (n *WebhookNotifier) OnBookmark(obj runtime.Object, initialEventsEnd bool) {}
+
+// Run delivers the queued notifications until ctx is cancelled.
+func (n *WebhookNotifier) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case notification := <-n.queue:
+			// Spare a webhook that keeps failing
+			if !n.breaker.allow() {
+				continue
+			}
+			err := n.deliver(ctx, notification)
+			n.breaker.record(err)
+			if err != nil {
+				n.logger.Warn("Failed to deliver webhook notification", "url", n.url, "namespace", notification.Namespace, "pod_name", notification.Name, "error", err)
+			}
+		}
+	}
+}
+
+// deliver posts a single notification.
+func (n *WebhookNotifier) deliver(ctx context.Context, notification deletionNotification) error {
+	body, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
+)
+
+// pushJob is the job label of the metrics pushed to a Pushgateway.
+const pushJob = "kube-api-streaming-demo"
+
+// pushMetrics pushes the watch counters and the runtime of the process to
+// the Pushgateway at url, for runs too short to be scraped.
+func pushMetrics(ctx context.Context, url string, runtime time.Duration) error {
+	registry := prometheus.NewRegistry()
+	if err := watcher.RegisterMetrics(registry); err != nil {
+		return err
+	}
+	runtimeSeconds := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "watch_runtime_seconds",
+		Help: "How long the watch ran before shutting down.",
+	})
+	runtimeSeconds.Set(runtime.Seconds())
+	if err := registry.Register(runtimeSeconds); err != nil {
+		return err
+	}
+	return push.New(url, pushJob).Gatherer(registry).PushContext(ctx)
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/sanjimoh/kube-api-streaming-demo/pkg/watcher"
+)
+
+// shutdownTimeout bounds how long an HTTP server may take to drain on exit.
+const shutdownTimeout = 5 * time.Second
+
+// serveMetrics serves the Prometheus metrics on addr until ctx is cancelled.
+func serveMetrics(ctx context.Context, logger *slog.Logger, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	serve(ctx, logger, "metrics", addr, mux)
+}
+
+// serveHealth serves the liveness and readiness probes on addr until ctx is
+// cancelled. /healthz always succeeds while /readyz only succeeds once ready
+// reports true. /debug/state returns the internal state of the watches as
+// JSON.
+func serveHealth(ctx context.Context, logger *slog.Logger, addr string, ready func() bool, states func() []watcher.State) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if !ready() {
+			http.Error(w, "initial events not received yet", http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok\n"))
+	})
+	mux.HandleFunc("/debug/state", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(states()); err != nil {
+			logger.Warn("Failed to write debug state", "error", err)
+		}
+	})
+	serve(ctx, logger, "health", addr, mux)
+}
+
+// serve runs an HTTP server for handler on addr and shuts it down once ctx is
+// cancelled.
+func serve(ctx context.Context, logger *slog.Logger, name, addr string, handler http.Handler) {
+	server := &http.Server{Addr: addr, Handler: handler}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			logger.Warn("Failed to shut down server", "server", name, "error", err)
+		}
+	}()
+
+	logger.Info("Starting server", "server", name, "addr", addr)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Error("Server failed", "server", name, "error", err)
+	}
+}
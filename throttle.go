@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// throttleRetryWindow is how long a throttled request is remembered while
+// waiting for client-go to retry it, which it does after the Retry-After
+// delay of a few seconds.
+const throttleRetryWindow = time.Minute
+
+// throttleRoundTripper is an http.RoundTripper logging the 429 Too Many
+// Requests responses of the API server along with their Retry-After header,
+// and how long client-go backed off before sending the request again.
+type throttleRoundTripper struct {
+	logger *slog.Logger
+	next   http.RoundTripper
+
+	mu sync.Mutex
+	// throttled holds when each throttled request was rejected, by method
+	// and URL, until it is retried or throttleRetryWindow has passed.
+	throttled map[string]time.Time
+}
+
+// newThrottleRoundTripper returns a throttleRoundTripper sending the
+// requests through next.
+func newThrottleRoundTripper(logger *slog.Logger, next http.RoundTripper) *throttleRoundTripper {
+	return &throttleRoundTripper{
+		logger:    logger,
+		next:      next,
+		throttled: make(map[string]time.Time),
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *throttleRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	key := req.Method + " " + req.URL.String()
+	t.mu.Lock()
+	rejectedAt, retry := t.throttled[key]
+	delete(t.throttled, key)
+	t.mu.Unlock()
+	retry = retry && time.Since(rejectedAt) <= throttleRetryWindow
+	if retry {
+		t.logger.Info("Retrying request after server-side throttling", "method", req.Method, "url", req.URL.String(), "backoff", time.Since(rejectedAt).Round(time.Millisecond))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests {
+		return resp, err
+	}
+	attrs := []any{"method", req.Method, "url", req.URL.String()}
+	if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		attrs = append(attrs, "retry_after", time.Duration(seconds)*time.Second)
+	}
+	t.logger.Warn("API server is throttling requests (429 Too Many Requests), consider lowering -qps and -burst", attrs...)
+	now := time.Now()
+	t.mu.Lock()
+	// Forget the requests that were given up on instead of retried
+	for other, at := range t.throttled {
+		if now.Sub(at) > throttleRetryWindow {
+			delete(t.throttled, other)
+		}
+	}
+	t.throttled[key] = now
+	t.mu.Unlock()
+	return resp, nil
+}
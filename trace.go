@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// redactedHeaders lists the request headers whose values are never logged.
+var redactedHeaders = map[string]bool{
+	"Authorization": true,
+}
+
+// tracingRoundTripper is an http.RoundTripper logging every request sent to
+// the API server together with its response status, to show how client-go
+// translates the watch options into HTTP.
+type tracingRoundTripper struct {
+	logger *slog.Logger
+	next   http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *tracingRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	attrs := []any{"method", req.Method, "url", req.URL.String(), "headers", redactHeaders(req.Header), "duration", time.Since(start).Round(time.Millisecond)}
+	if err != nil {
+		t.logger.Warn("HTTP request failed", append(attrs, "error", err)...)
+		return resp, err
+	}
+	t.logger.Info("HTTP request", append(attrs, "status", resp.StatusCode)...)
+	return resp, nil
+}
+
+// redactHeaders returns the headers to log, hiding credentials.
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name, values := range header {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] {
+			headers[name] = "REDACTED"
+			continue
+		}
+		if len(values) > 0 {
+			headers[name] = values[0]
+		}
+	}
+	return headers
+}
@@ -0,0 +1,18 @@
+package main
+
+import "log/slog"
+
+// warningLogger is a rest.WarningHandler logging the warnings sent by the API
+// server, e.g. deprecation notices, instead of printing them to stderr.
+type warningLogger struct {
+	logger *slog.Logger
+}
+
+// HandleWarningHeader implements rest.WarningHandler.
+func (w warningLogger) HandleWarningHeader(code int, agent string, text string) {
+	// Only 299 is a deprecation or other warning about the request
+	if code != 299 || text == "" {
+		return
+	}
+	w.logger.Warn("WARNING: "+text, "agent", agent)
+}